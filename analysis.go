@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// KillmailResult is the outcome of fetching a single killmail of a zKillboard listing.
+type KillmailResult struct {
+	// Index is the position of the killmail in the listing it came from.
+	Index    int
+	Killmail Killmail
+	Err      error
+}
+
+// FetchKillmails fetches the listed killmails from ESI with at most workers requests in flight.
+// Results are delivered as soon as they complete, so they arrive out of order. The channel is
+// closed once every killmail is done or the context is cancelled.
+func FetchKillmails(ctx context.Context, refs []ZKillmail, workers int) <-chan KillmailResult {
+	if workers < 1 {
+		workers = 1
+	}
+
+	jobs := make(chan int)
+	results := make(chan KillmailResult)
+
+	go func() {
+		defer close(jobs)
+		for i := range refs {
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				killmail, err := GetKillmail(ctx, refs[i].KillmailID, refs[i].ZKB.Hash)
+				select {
+				case results <- KillmailResult{Index: i, Killmail: killmail, Err: err}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	return results
+}
+
+// LossRow is a row of the loss table together with the time of the loss it describes.
+type LossRow struct {
+	Time  time.Time
+	Cells []string
+	// Values holds the cell of every loss column by column ID, so the visible columns can change
+	// without summarizing the loss again. It is nil while the loss is pending.
+	Values map[string]string
+	// Roles are the fitted modules of the loss, kept to compute the cells again when the columns change.
+	Roles []ModuleRole
+	// Killmail is the loss the row describes, and Names the names of its types, nil while pending.
+	Killmail Killmail
+	Names    map[int]ResolvedName
+}
+
+// Resolved reports whether the items of the loss are known, so its fit can be shown.
+func (r LossRow) Resolved() bool {
+	return r.Names != nil
+}
+
+// NamedFit returns the fit of the loss ready for export, named after the killmail.
+func (r LossRow) NamedFit() NamedFit {
+	return NamedFit{
+		Name:  fmt.Sprintf("Killmail %d", r.Killmail.KillmailID),
+		Fit:   FitFromKillmail(r.Killmail),
+		Names: r.Names,
+	}
+}
+
+// FitEFT renders the fit of the loss in EFT format, named after the killmail.
+func (r LossRow) FitEFT() string {
+	fit := r.NamedFit()
+	return fit.Fit.EFT(fit.Names, fit.Name)
+}
+
+// lossCells lays out the cells of a loss for the given columns.
+func lossCells(t time.Time, values map[string]string, columns []LossColumn) []string {
+	cells := []string{lossDate(t)}
+	for _, column := range columns {
+		if values == nil {
+			cells = append(cells, "...")
+		} else {
+			cells = append(cells, values[column.ID])
+		}
+	}
+	return cells
+}
+
+// RefreshLossRows lays the rows out again for the columns currently visible, computing the
+// cells again from the fitted modules when they are known.
+func RefreshLossRows(rows []LossRow) {
+	columns := VisibleLossColumns()
+	for i := range rows {
+		if rows[i].Roles != nil {
+			rows[i].Values = lossColumnValues(rows[i].Roles)
+		}
+		rows[i].Cells = lossCells(rows[i].Time, rows[i].Values, columns)
+	}
+}
+
+// SortLossRows orders rows from the most recent loss to the oldest.
+func SortLossRows(rows []LossRow) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].Time.After(rows[j].Time)
+	})
+}
+
+// LossTable returns the header followed by the cells of every row.
+func LossTable(rows []LossRow) [][]string {
+	table := [][]string{lossTableHeader(VisibleLossColumns())}
+	for _, row := range rows {
+		table = append(table, row.Cells)
+	}
+	return table
+}
+
+// ResolveKillmailTypes resolves the names of every type found on the killmails in a single
+// batched lookup, rather than one lookup per killmail.
+func ResolveKillmailTypes(ctx context.Context, killmails []Killmail) (map[int]ResolvedName, error) {
+	ids := make([]int, 0)
+	for _, killmail := range killmails {
+		ids = append(ids, killmail.TypeIDs()...)
+	}
+
+	return ResolveIDs(ctx, unique(ids))
+}
+
+// lossDate formats the time of a loss, relative to now for recent losses.
+func lossDate(t time.Time) string {
+	if time.Now().Sub(t).Hours() < 24*31 {
+		return fmt.Sprintf("%v days ago", int(time.Now().Sub(t).Hours()/24))
+	}
+	return fmt.Sprintf("%v", t.Format("2006-01-02"))
+}
+
+// pendingLossRow is the row shown for a killmail whose items are not resolved yet.
+func pendingLossRow(killmail Killmail) LossRow {
+	return LossRow{
+		Time:     killmail.KillmailTime,
+		Cells:    lossCells(killmail.KillmailTime, nil, VisibleLossColumns()),
+		Killmail: killmail,
+	}
+}
+
+// summarizeLoss builds the loss table row of a killmail from the roles of its fitted modules.
+func summarizeLoss(killmail Killmail, names map[int]ResolvedName) LossRow {
+	// Only count fitted modules, not spares in cargo, drones or loaded charges.
+	roles := ClassifyModules(FitFromKillmail(killmail), names)
+	values := lossColumnValues(roles)
+
+	return LossRow{
+		Time:     killmail.KillmailTime,
+		Cells:    lossCells(killmail.KillmailTime, values, VisibleLossColumns()),
+		Values:   values,
+		Roles:    roles,
+		Killmail: killmail,
+		Names:    names,
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFetchKillmailsBoundsConcurrency(t *testing.T) {
+	var inFlight, maxInFlight int32
+	newFakeESI(t, func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			seen := atomic.LoadInt32(&maxInFlight)
+			if current <= seen || atomic.CompareAndSwapInt32(&maxInFlight, seen, current) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		var id int
+		fmt.Sscanf(r.URL.Path, "/killmails/%d/", &id)
+		fmt.Fprintf(w, `{"killmail_id": %d, "killmail_time": "2023-06-01T12:00:00Z", "victim": {"ship_type_id": 587}}`, id)
+	})
+
+	refs := make([]ZKillmail, 10)
+	for i := range refs {
+		refs[i].KillmailID = 950000000 + i
+		refs[i].ZKB.Hash = "hash"
+	}
+
+	seen := make(map[int]bool)
+	for result := range FetchKillmails(context.Background(), refs, 3) {
+		if result.Err != nil {
+			t.Errorf("Error occurred: %v", result.Err)
+			continue
+		}
+		if result.Killmail.KillmailID != refs[result.Index].KillmailID {
+			t.Errorf("Result %d holds killmail %d", result.Index, result.Killmail.KillmailID)
+		}
+		seen[result.Index] = true
+	}
+
+	if len(seen) != len(refs) {
+		t.Errorf("Expected %d killmails, got: %d", len(refs), len(seen))
+	}
+	if maxInFlight > 3 {
+		t.Errorf("Expected at most 3 requests in flight, got: %d", maxInFlight)
+	}
+}
+
+func TestSortLossRows(t *testing.T) {
+	now := time.Now()
+	rows := []LossRow{
+		{Time: now.Add(-48 * time.Hour), Cells: []string{"b"}},
+		{Time: now, Cells: []string{"a"}},
+		{Time: now.Add(-72 * time.Hour), Cells: []string{"c"}},
+	}
+	SortLossRows(rows)
+
+	table := LossTable(rows)
+	if len(table) != 4 || table[1][0] != "a" || table[2][0] != "b" || table[3][0] != "c" {
+		t.Errorf("Unexpected table order: %v", table)
+	}
+}
+
+func TestResolveKillmailTypesInOneRequest(t *testing.T) {
+	calls := newFakeNamesESI(t, map[int]ResolvedName{
+		960000001: {ID: 960000001, Name: "Rifter", Category: CategoryInventoryType},
+		960000002: {ID: 960000002, Name: "Warp Scrambler II", Category: CategoryInventoryType},
+		960000003: {ID: 960000003, Name: "Stasis Webifier II", Category: CategoryInventoryType},
+		960000004: {ID: 960000004, Name: "1MN Afterburner II", Category: CategoryInventoryType},
+	})
+
+	killmails := []Killmail{
+		{Victim: Victim{ShipTypeID: 960000001, Items: []KillmailItem{
+			{ItemTypeID: 960000002, Flag: 19, QuantityDestroyed: 1},
+			{ItemTypeID: 960000003, Flag: 20, QuantityDestroyed: 1},
+			{ItemTypeID: 960000003, Flag: 21, QuantityDropped: 1},
+			{ItemTypeID: 960000004, Flag: 22, QuantityDropped: 1},
+		}}},
+		{Victim: Victim{ShipTypeID: 960000001, Items: []KillmailItem{
+			{ItemTypeID: 960000002, Flag: 5, QuantityDropped: 1},
+			{ItemTypeID: 960000003, Flag: 19, QuantityDestroyed: 1},
+		}}},
+	}
+
+	names, err := ResolveKillmailTypes(context.Background(), killmails)
+	if err != nil {
+		t.Errorf("Error occurred: %v", err)
+		return
+	}
+	if *calls != 1 {
+		t.Errorf("Expected a single request, got: %d", *calls)
+	}
+
+	expected := [][]string{
+		{"1MN AB", "O", "X", "2", "X", "X"},
+		{"X", "X", "X", "1", "X", "X"},
+	}
+	for i, killmail := range killmails {
+		row := summarizeLoss(killmail, names)
+		if fmt.Sprint(row.Cells[1:]) != fmt.Sprint(expected[i]) {
+			t.Errorf("Expected row %v, got: %v", expected[i], row.Cells[1:])
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import "sync"
+
+// chunk splits items into consecutive slices holding at most size elements each.
+func chunk[T any](items []T, size int) [][]T {
+	if size < 1 || size > len(items) {
+		size = len(items)
+	}
+
+	chunks := make([][]T, 0)
+	for start := 0; start < len(items); start += size {
+		end := start + size
+		if end > len(items) {
+			end = len(items)
+		}
+		chunks = append(chunks, items[start:end])
+	}
+	return chunks
+}
+
+// runBatches splits items into chunks of at most size elements and calls fn on each of them,
+// running no more than KESIBatchConcurrency calls at once. Results are merged in chunk order
+// and the first error encountered is returned.
+func runBatches[T any, R any](items []T, size int, fn func([]T) ([]R, error)) ([]R, error) {
+	chunks := chunk(items, size)
+	if len(chunks) == 0 {
+		return nil, nil
+	} else if len(chunks) == 1 {
+		return fn(chunks[0])
+	}
+
+	results := make([][]R, len(chunks))
+	errs := make([]error, len(chunks))
+	semaphore := make(chan struct{}, KESIBatchConcurrency)
+
+	var wg sync.WaitGroup
+	for i, c := range chunks {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int, c []T) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			results[i], errs[i] = fn(c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	merged := make([]R, 0)
+	for i := range chunks {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		merged = append(merged, results[i]...)
+	}
+	return merged, nil
+}
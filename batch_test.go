@@ -0,0 +1,45 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChunk(t *testing.T) {
+	chunks := chunk([]int{1, 2, 3, 4, 5}, 2)
+	expected := [][]int{{1, 2}, {3, 4}, {5}}
+
+	if !reflect.DeepEqual(chunks, expected) {
+		t.Errorf("Expected chunks: %v, got: %v", expected, chunks)
+	}
+
+	if len(chunk([]int{}, 2)) != 0 {
+		t.Errorf("Expected no chunks for an empty slice")
+	}
+}
+
+func TestRunBatchesKeepsOrder(t *testing.T) {
+	items := make([]int, 0)
+	for i := 0; i < 95; i++ {
+		items = append(items, i)
+	}
+
+	result, err := runBatches(items, 10, func(batch []int) ([]int, error) {
+		doubled := make([]int, len(batch))
+		for i, item := range batch {
+			doubled[i] = item * 2
+		}
+		return doubled, nil
+	})
+	if err != nil {
+		t.Errorf("Error occurred: %v", err)
+		return
+	}
+
+	for i, item := range result {
+		if item != i*2 {
+			t.Errorf("Expected %d at %d, got: %d", i*2, i, item)
+			return
+		}
+	}
+}
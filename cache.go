@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// Cache stores raw values under string keys. Implementations must be safe for concurrent use.
+type Cache interface {
+	// Get returns the value stored for key, if it exists and has not expired.
+	Get(key string) ([]byte, bool)
+	// Set stores value for key. A ttl of zero keeps the value until it is deleted.
+	Set(key string, value []byte, ttl time.Duration)
+	// Delete removes key from the cache.
+	Delete(key string)
+}
+
+// MemoryCache is a Cache kept in memory only.
+type MemoryCache struct {
+	mu      sync.RWMutex
+	entries map[string]memoryCacheEntry
+}
+
+type memoryCacheEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+// NewMemoryCache creates an empty MemoryCache.
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: make(map[string]memoryCacheEntry)}
+}
+
+func (c *MemoryCache) Get(key string) ([]byte, bool) {
+	c.mu.RLock()
+	entry, ok := c.entries[key]
+	c.mu.RUnlock()
+
+	if !ok {
+		return nil, false
+	}
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		c.Delete(key)
+		return nil, false
+	}
+	return entry.value, true
+}
+
+func (c *MemoryCache) Set(key string, value []byte, ttl time.Duration) {
+	entry := memoryCacheEntry{value: value}
+	if ttl > 0 {
+		entry.expires = time.Now().Add(ttl)
+	}
+
+	c.mu.Lock()
+	c.entries[key] = entry
+	c.mu.Unlock()
+}
+
+func (c *MemoryCache) Delete(key string) {
+	c.mu.Lock()
+	delete(c.entries, key)
+	c.mu.Unlock()
+}
+
+// sharedCache is the cache used by every resolver. It starts in memory and is
+// replaced by the persistent cache on startup.
+var sharedCache Cache = NewMemoryCache()
+
+// SetCache replaces the cache used by the resolvers.
+func SetCache(cache Cache) {
+	sharedCache = cache
+}
+
+// getCachedJSON decodes the value stored for key into v.
+func getCachedJSON(cache Cache, key string, v interface{}) bool {
+	if cache == nil {
+		return false
+	}
+
+	data, ok := cache.Get(key)
+	if !ok {
+		return false
+	}
+	return json.Unmarshal(data, v) == nil
+}
+
+// setCachedJSON encodes v and stores it for key.
+func setCachedJSON(cache Cache, key string, v interface{}, ttl time.Duration) {
+	if cache == nil {
+		return
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	cache.Set(key, data, ttl)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryCacheExpiry(t *testing.T) {
+	cache := NewMemoryCache()
+	cache.Set("forever", []byte("1"), 0)
+	cache.Set("short", []byte("2"), time.Millisecond)
+
+	time.Sleep(5 * time.Millisecond)
+
+	if value, ok := cache.Get("forever"); !ok || string(value) != "1" {
+		t.Errorf("Expected the entry without ttl to stay, got: %s", value)
+	}
+	if _, ok := cache.Get("short"); ok {
+		t.Errorf("Expected the expired entry to be gone")
+	}
+
+	cache.Delete("forever")
+	if _, ok := cache.Get("forever"); ok {
+		t.Errorf("Expected the deleted entry to be gone")
+	}
+}
+
+func TestConcurrentResolution(t *testing.T) {
+	known := map[int]ResolvedName{}
+	ids := []int{}
+	for id := 940000000; id < 940000050; id++ {
+		known[id] = ResolvedName{ID: id, Name: "Module", Category: CategoryInventoryType}
+		ids = append(ids, id)
+	}
+	newFakeNamesESI(t, known)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(offset int) {
+			defer wg.Done()
+			resolved, err := ResolveIDsOrdered(context.Background(), ids[offset:])
+			if err != nil {
+				t.Errorf("Error occurred: %v", err)
+				return
+			}
+			if len(resolved) != len(ids)-offset {
+				t.Errorf("Expected %d names, got: %d", len(ids)-offset, len(resolved))
+			}
+		}(i)
+	}
+	wg.Wait()
+}
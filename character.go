@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CharacterInfo is the public information ESI publishes about a character.
+type CharacterInfo struct {
+	Name           string    `json:"name"`
+	CorporationID  int       `json:"corporation_id"`
+	AllianceID     int       `json:"alliance_id,omitempty"`
+	FactionID      int       `json:"faction_id,omitempty"`
+	Birthday       time.Time `json:"birthday"`
+	SecurityStatus float64   `json:"security_status"`
+	Title          string    `json:"title,omitempty"`
+}
+
+// CorporationInfo is the public information ESI publishes about a corporation.
+type CorporationInfo struct {
+	Name        string `json:"name"`
+	Ticker      string `json:"ticker"`
+	AllianceID  int    `json:"alliance_id,omitempty"`
+	FactionID   int    `json:"faction_id,omitempty"`
+	MemberCount int    `json:"member_count"`
+}
+
+// AllianceInfo is the public information ESI publishes about an alliance.
+type AllianceInfo struct {
+	Name   string `json:"name"`
+	Ticker string `json:"ticker"`
+}
+
+// CharacterProfile gathers who a character is: their corporation, alliance and faction.
+type CharacterProfile struct {
+	CharacterID int
+	Character   CharacterInfo
+	Corporation CorporationInfo
+	// Alliance is nil when the corporation is not in an alliance.
+	Alliance    *AllianceInfo
+	FactionName string
+}
+
+// GetCharacterProfile fetches the public profile of a character along with the names and tickers of its corporation and alliance.
+func GetCharacterProfile(ctx context.Context, characterID int) (CharacterProfile, error) {
+	profile := CharacterProfile{CharacterID: characterID}
+
+	err := esiClient.getJSON(ctx, fmt.Sprintf("/characters/%d/", characterID), nil, &profile.Character)
+	if err != nil {
+		return CharacterProfile{}, err
+	}
+
+	err = esiClient.getJSON(ctx, fmt.Sprintf("/corporations/%d/", profile.Character.CorporationID), nil, &profile.Corporation)
+	if err != nil {
+		return CharacterProfile{}, err
+	}
+
+	if profile.Character.AllianceID != 0 {
+		var alliance AllianceInfo
+		err = esiClient.getJSON(ctx, fmt.Sprintf("/alliances/%d/", profile.Character.AllianceID), nil, &alliance)
+		if err != nil {
+			return CharacterProfile{}, err
+		}
+		profile.Alliance = &alliance
+	}
+
+	if profile.Character.FactionID != 0 {
+		names, err := ResolveIDs(ctx, []int{profile.Character.FactionID})
+		if err != nil {
+			return CharacterProfile{}, err
+		}
+		profile.FactionName = names[profile.Character.FactionID].Name
+	}
+
+	return profile, nil
+}
+
+// Age returns how long ago the character was created.
+func (p CharacterProfile) Age() time.Duration {
+	return time.Since(p.Character.Birthday)
+}
+
+// IsYoung reports whether the character is younger than KYoungCharacterAge, a likely alt.
+func (p CharacterProfile) IsYoung() bool {
+	return p.Age() < KYoungCharacterAge
+}
+
+// Text renders the profile as the few lines shown above the ship list.
+func (p CharacterProfile) Text() string {
+	lines := make([]string, 0)
+
+	title := fmt.Sprintf("%s [%s]", p.Character.Name, p.Corporation.Ticker)
+	if p.Alliance != nil {
+		title += fmt.Sprintf(" <%s>", p.Alliance.Ticker)
+	}
+	lines = append(lines, title)
+
+	affiliation := p.Corporation.Name
+	if p.Alliance != nil {
+		affiliation += " / " + p.Alliance.Name
+	}
+	lines = append(lines, affiliation)
+
+	details := fmt.Sprintf("Born %s (%s), sec %.1f", p.Character.Birthday.Format("2006-01-02"), formatAge(p.Age()), p.Character.SecurityStatus)
+	if p.FactionName != "" {
+		details += ", " + p.FactionName
+	}
+	lines = append(lines, details)
+
+	if p.IsYoung() {
+		lines = append(lines, fmt.Sprintf("Warning: only %s old", formatAge(p.Age())))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// formatAge formats a duration in days, months or years.
+func formatAge(d time.Duration) string {
+	days := int(d.Hours() / 24)
+	switch {
+	case days < 60:
+		return fmt.Sprintf("%d days", days)
+	case days < 730:
+		return fmt.Sprintf("%d months", days/30)
+	default:
+		return fmt.Sprintf("%d years", days/365)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetCharacterProfile(t *testing.T) {
+	birthday := time.Now().Add(-14 * 24 * time.Hour).UTC().Format(time.RFC3339)
+	newFakeESI(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/characters/970000001/":
+			fmt.Fprintf(w, `{"name": "Fresh Alt", "corporation_id": 970000002, "alliance_id": 970000003, "birthday": %q, "security_status": -1.25}`, birthday)
+		case "/corporations/970000002/":
+			fmt.Fprint(w, `{"name": "Spy Corp", "ticker": "SPY", "alliance_id": 970000003, "member_count": 12}`)
+		case "/alliances/970000003/":
+			fmt.Fprint(w, `{"name": "Known Hostiles", "ticker": "HOST"}`)
+		default:
+			t.Errorf("Unexpected path: %v", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	profile, err := GetCharacterProfile(context.Background(), 970000001)
+	if err != nil {
+		t.Errorf("Error occurred: %v", err)
+		return
+	}
+
+	if profile.Corporation.Ticker != "SPY" || profile.Alliance == nil || profile.Alliance.Ticker != "HOST" {
+		t.Errorf("Unexpected profile: %+v", profile)
+	}
+	if !profile.IsYoung() {
+		t.Errorf("Expected a two week old character to be flagged as young")
+	}
+
+	text := profile.Text()
+	for _, expected := range []string{"Fresh Alt [SPY] <HOST>", "Spy Corp / Known Hostiles", "sec -1.2", "Warning: only 14 days old"} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("Expected %q in profile text, got: %v", expected, text)
+		}
+	}
+}
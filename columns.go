@@ -0,0 +1,294 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// How a loss column summarizes the modules it matches.
+const (
+	// AggregatePresence shows O when a matching module is fitted.
+	AggregatePresence = "presence"
+	// AggregateCount shows how many matching modules are fitted.
+	AggregateCount = "count"
+	// AggregateMaxSize shows the size of the largest matching propulsion module.
+	AggregateMaxSize = "max_size"
+	// AggregatePropulsion describes every matching propulsion module, see ClassifyPropulsion.
+	AggregatePropulsion = "propulsion"
+)
+
+// LossColumn is a column of the loss table, summarizing the fitted modules it matches.
+// A module matches when its kind, type ID or group ID is listed, or a name pattern matches its name.
+type LossColumn struct {
+	// ID identifies the column in the saved column selection.
+	ID       string       `json:"id"`
+	Header   string       `json:"header"`
+	Kinds    []ModuleKind `json:"kinds,omitempty"`
+	TypeIDs  []int        `json:"type_ids,omitempty"`
+	GroupIDs []int        `json:"group_ids,omitempty"`
+	// Names are regular expressions matched against type names, ignoring case.
+	Names []string `json:"names,omitempty"`
+	// Aggregate is one of the Aggregate* constants, AggregatePresence when empty.
+	Aggregate string `json:"aggregate,omitempty"`
+	// Hidden columns are only shown once the user picks them.
+	Hidden bool `json:"hidden,omitempty"`
+
+	namePatterns []*regexp.Regexp
+}
+
+// compile checks the column and prepares its name patterns.
+func (c *LossColumn) compile() error {
+	if c.ID == "" || c.Header == "" {
+		return fmt.Errorf("column %q needs both an id and a header", c.ID+c.Header)
+	}
+
+	switch c.Aggregate {
+	case "":
+		c.Aggregate = AggregatePresence
+	case AggregatePresence, AggregateCount, AggregateMaxSize, AggregatePropulsion:
+	default:
+		return fmt.Errorf("column %q has unknown aggregate %q", c.ID, c.Aggregate)
+	}
+
+	c.namePatterns = make([]*regexp.Regexp, 0, len(c.Names))
+	for _, name := range c.Names {
+		pattern, err := regexp.Compile("(?i)" + name)
+		if err != nil {
+			return fmt.Errorf("column %q has invalid name pattern: %w", c.ID, err)
+		}
+		c.namePatterns = append(c.namePatterns, pattern)
+	}
+	return nil
+}
+
+// Matches reports whether a fitted module belongs in the column.
+func (c LossColumn) Matches(role ModuleRole) bool {
+	for _, kind := range c.Kinds {
+		if role.Kind == kind && kind != KindOther {
+			return true
+		}
+	}
+	for _, id := range c.TypeIDs {
+		if role.TypeID == id {
+			return true
+		}
+	}
+	for _, id := range c.GroupIDs {
+		if role.GroupID == id && id != 0 {
+			return true
+		}
+	}
+	for _, pattern := range c.namePatterns {
+		if role.Name != "" && pattern.MatchString(role.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Value summarizes the fitted modules as the cell of the column.
+func (c LossColumn) Value(roles []ModuleRole) string {
+	matched := make([]ModuleRole, 0)
+	for _, role := range roles {
+		if c.Matches(role) {
+			matched = append(matched, role)
+		}
+	}
+
+	switch {
+	case c.Aggregate == AggregatePropulsion:
+		return ClassifyPropulsion(matched).String()
+	case len(matched) == 0:
+		return "X"
+	case c.Aggregate == AggregateCount:
+		return strconv.Itoa(len(matched))
+	case c.Aggregate == AggregateMaxSize:
+		largest := matched[0]
+		for _, role := range matched[1:] {
+			if role.SizeMN > largest.SizeMN {
+				largest = role
+			}
+		}
+		if largest.SizeMN == 0 {
+			return largest.HullSize
+		}
+		return fmt.Sprintf("%vMN", largest.SizeMN)
+	default:
+		return "O"
+	}
+}
+
+// lossPropColumn is the column describing the propulsion of a fit.
+const lossPropColumn = "prop"
+
+// builtinLossColumns lists the columns the loss table has without a rules file, in display order.
+var builtinLossColumns = []LossColumn{
+	{ID: lossPropColumn, Header: "Prop", Kinds: []ModuleKind{KindAfterburner, KindMicrowarpdrive, KindMicroJumpDrive, KindMicroJumpFieldGenerator}, Aggregate: AggregatePropulsion},
+	{ID: "scram", Header: "Scram", Kinds: []ModuleKind{KindWarpScrambler}},
+	{ID: "point", Header: "Point", Kinds: []ModuleKind{KindWarpDisruptor}},
+	{ID: "web", Header: "Web", Kinds: []ModuleKind{KindStasisWeb, KindStasisGrappler}, Aggregate: AggregateCount},
+	{ID: "neut", Header: "Neut", Kinds: []ModuleKind{KindEnergyNeutralizer}},
+	{ID: "damp", Header: "Damp", Kinds: []ModuleKind{KindSensorDampener}},
+	{ID: "nos", Header: "Nos", Kinds: []ModuleKind{KindEnergyNosferatu}, Hidden: true},
+	{ID: "ecm", Header: "ECM", Kinds: []ModuleKind{KindECM}, Aggregate: AggregateCount, Hidden: true},
+	{ID: "td", Header: "TD", Kinds: []ModuleKind{KindTrackingDisruptor}, Hidden: true},
+	{ID: "gd", Header: "GD", Kinds: []ModuleKind{KindGuidanceDisruptor}, Hidden: true},
+	{ID: "tp", Header: "TP", Kinds: []ModuleKind{KindTargetPainter}, Hidden: true},
+	{ID: "bubble", Header: "Bubble", Kinds: []ModuleKind{KindWarpDisruptFieldGenerator, KindInterdictionSphereLauncher}, Hidden: true},
+	{ID: "hic", Header: "HIC", Kinds: []ModuleKind{KindHeavyWarpScrambler, KindHeavyWarpDisruptor}, Hidden: true},
+	{ID: "cloak", Header: "Cloak", Kinds: []ModuleKind{KindCloak}, Hidden: true},
+	{ID: "cyno", Header: "Cyno", Kinds: []ModuleKind{KindCynosuralField}, Hidden: true},
+	{ID: "rr", Header: "RR", Kinds: []ModuleKind{KindRemoteArmorRepairer, KindRemoteShieldBooster}, Aggregate: AggregateCount, Hidden: true},
+	{ID: "asb", Header: "ASB", Kinds: []ModuleKind{KindAncillaryShieldBooster}, Hidden: true},
+	{ID: "aar", Header: "AAR", Kinds: []ModuleKind{KindAncillaryArmorRepairer}, Hidden: true},
+	{ID: "wcs", Header: "WCS", Kinds: []ModuleKind{KindWarpCoreStabilizer}, Aggregate: AggregateCount, Hidden: true},
+}
+
+// defaultLossColumns are the columns shown until the user picks their own.
+var defaultLossColumns = []string{lossPropColumn, "scram", "point", "web", "neut", "damp"}
+
+var (
+	lossColumns = mustCompileColumns(builtinLossColumns)
+	// lossColumnChoices holds whether the user picked each column, by ID. Columns the user
+	// never picked or dropped follow their Hidden default.
+	lossColumnChoices = make(map[string]bool)
+	lossColumnsMu     sync.RWMutex
+)
+
+func mustCompileColumns(columns []LossColumn) []LossColumn {
+	compiled := make([]LossColumn, len(columns))
+	copy(compiled, columns)
+	for i := range compiled {
+		if err := compiled[i].compile(); err != nil {
+			panic(err)
+		}
+	}
+	return compiled
+}
+
+// LossColumns returns every column the loss table can show.
+func LossColumns() []LossColumn {
+	lossColumnsMu.RLock()
+	defer lossColumnsMu.RUnlock()
+	return lossColumns
+}
+
+// SetLossColumns replaces the columns the loss table can show, keeping the user's picks.
+func SetLossColumns(columns []LossColumn) {
+	lossColumnsMu.Lock()
+	defer lossColumnsMu.Unlock()
+	lossColumns = columns
+}
+
+// VisibleLossColumns returns the columns currently shown, in display order.
+func VisibleLossColumns() []LossColumn {
+	lossColumnsMu.RLock()
+	defer lossColumnsMu.RUnlock()
+
+	columns := make([]LossColumn, 0, len(lossColumns))
+	for _, column := range lossColumns {
+		visible, picked := lossColumnChoices[column.ID]
+		if visible || (!picked && !column.Hidden) {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// VisibleLossColumnIDs returns the IDs of the columns currently shown.
+func VisibleLossColumnIDs() []string {
+	columns := VisibleLossColumns()
+	ids := make([]string, 0, len(columns))
+	for _, column := range columns {
+		ids = append(ids, column.ID)
+	}
+	return ids
+}
+
+// SetVisibleLossColumns shows the columns with the given IDs and hides every other current column.
+// Unknown IDs are ignored.
+func SetVisibleLossColumns(ids []string) {
+	lossColumnsMu.Lock()
+	defer lossColumnsMu.Unlock()
+
+	visible := make(map[string]bool)
+	for _, id := range ids {
+		visible[id] = true
+	}
+	for _, column := range lossColumns {
+		lossColumnChoices[column.ID] = visible[column.ID]
+	}
+}
+
+// LossColumnChoices formats the user's column picks for saving, e.g. "cyno=1,damp=0".
+func LossColumnChoices() string {
+	lossColumnsMu.RLock()
+	defer lossColumnsMu.RUnlock()
+
+	choices := make([]string, 0, len(lossColumnChoices))
+	for _, column := range lossColumns {
+		if visible, picked := lossColumnChoices[column.ID]; picked {
+			choices = append(choices, fmt.Sprintf("%s=%v", column.ID, boolDigit(visible)))
+		}
+	}
+	// Keep the picks of columns the rules file no longer defines, it may define them again.
+	for id, visible := range lossColumnChoices {
+		if !hasColumn(lossColumns, id) {
+			choices = append(choices, fmt.Sprintf("%s=%v", id, boolDigit(visible)))
+		}
+	}
+	return strings.Join(choices, ",")
+}
+
+// SetLossColumnChoices restores picks saved by LossColumnChoices. A bare ID counts as picked.
+func SetLossColumnChoices(saved string) {
+	lossColumnsMu.Lock()
+	defer lossColumnsMu.Unlock()
+
+	lossColumnChoices = make(map[string]bool)
+	for _, choice := range strings.Split(saved, ",") {
+		id, value, found := strings.Cut(strings.TrimSpace(choice), "=")
+		if id == "" {
+			continue
+		}
+		lossColumnChoices[id] = !found || value == "1"
+	}
+}
+
+func boolDigit(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func hasColumn(columns []LossColumn, id string) bool {
+	for _, column := range columns {
+		if column.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// lossTableHeader returns the header row of the loss table for the given columns.
+func lossTableHeader(columns []LossColumn) []string {
+	header := []string{"Date"}
+	for _, column := range columns {
+		header = append(header, column.Header)
+	}
+	return header
+}
+
+// lossColumnValues computes the cell of every loss column from the roles of a fit.
+func lossColumnValues(roles []ModuleRole) map[string]string {
+	columns := LossColumns()
+	values := make(map[string]string, len(columns))
+	for _, column := range columns {
+		values[column.ID] = column.Value(roles)
+	}
+	return values
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestLossColumnValues(t *testing.T) {
+	roles := []ModuleRole{
+		{Kind: KindMicrowarpdrive, SizeMN: 5},
+		{Kind: KindWarpScrambler},
+		{Kind: KindStasisWeb},
+		{Kind: KindStasisGrappler},
+		{Kind: KindCynosuralField},
+		{Kind: KindRemoteArmorRepairer},
+		{Kind: KindRemoteShieldBooster},
+		{Kind: KindHeavyWarpDisruptor},
+	}
+
+	values := lossColumnValues(roles)
+	expected := map[string]string{
+		"prop":  "5MN MWD",
+		"scram": "O",
+		"point": "X",
+		"web":   "2",
+		"cyno":  "O",
+		"rr":    "2",
+		"hic":   "O",
+		"cloak": "X",
+	}
+	for id, value := range expected {
+		if values[id] != value {
+			t.Errorf("Expected %q in column %v, got: %q", value, id, values[id])
+		}
+	}
+	if len(values) != len(LossColumns()) {
+		t.Errorf("Expected a value for all %d columns, got: %d", len(LossColumns()), len(values))
+	}
+}
+
+func TestVisibleLossColumns(t *testing.T) {
+	defer SetVisibleLossColumns(defaultLossColumns)
+
+	now := time.Now()
+	rows := []LossRow{
+		{Time: now, Values: lossColumnValues([]ModuleRole{{Kind: KindCloak}, {Kind: KindAfterburner, SizeMN: 1}})},
+		{Time: now},
+	}
+
+	// Columns keep their display order whatever order they are picked in, unknown IDs are dropped.
+	SetVisibleLossColumns([]string{"cloak", "missing", "prop"})
+	RefreshLossRows(rows)
+
+	table := LossTable(rows)
+	expected := [][]string{
+		{"Date", "Prop", "Cloak"},
+		{lossDate(now), "1MN AB", "O"},
+		{lossDate(now), "...", "..."},
+	}
+	if fmt.Sprint(table) != fmt.Sprint(expected) {
+		t.Errorf("Expected table %v, got: %v", expected, table)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// esiCachedResponse is an ESI GET response kept for conditional requests.
+type esiCachedResponse struct {
+	ETag    string          `json:"etag,omitempty"`
+	Expires time.Time       `json:"expires"`
+	Body    json.RawMessage `json:"data"`
+}
+
+// fresh reports whether ESI considers the response current.
+func (r esiCachedResponse) fresh() bool {
+	return time.Now().Before(r.Expires)
+}
+
+// responseCache returns the cache holding the client's GET responses.
+func (c *ESIClient) responseCache() Cache {
+	if c.Cache != nil {
+		return c.Cache
+	}
+	return sharedCache
+}
+
+// getJSON fetches an ESI GET route and decodes the response into v. Responses are reused until
+// their Expires time, then revalidated with If-None-Match; a 304 Not Modified reuses the cached body.
+func (c *ESIClient) getJSON(ctx context.Context, path string, query url.Values, v interface{}) error {
+	req, err := c.newRequest(ctx, "GET", path, query, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+
+	cache := c.responseCache()
+	key := "esi:" + req.URL.String()
+
+	var cached esiCachedResponse
+	// Responses cached by earlier versions kept the body base64 encoded under another field and are refetched.
+	hasCached := getCachedJSON(cache, key, &cached) && len(cached.Body) > 0
+	if hasCached && cached.fresh() {
+		return json.Unmarshal(cached.Body, v)
+	}
+	if hasCached && cached.ETag != "" {
+		req.Header.Set("If-None-Match", cached.ETag)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make HTTP request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && hasCached {
+		cached.Expires = responseExpires(resp)
+		setCachedJSON(cache, key, cached, KCacheTTLETag)
+		return json.Unmarshal(cached.Body, v)
+	}
+
+	if err := checkResponse("ESI", resp); err != nil {
+		return err
+	}
+
+	// Decode while reading, keeping a copy of the body for revalidation.
+	var body bytes.Buffer
+	if err := json.NewDecoder(io.TeeReader(resp.Body, &body)).Decode(v); err != nil {
+		return fmt.Errorf("failed to unmarshal JSON response: %w", err)
+	}
+
+	setCachedJSON(cache, key, esiCachedResponse{
+		ETag:    resp.Header.Get("ETag"),
+		Expires: responseExpires(resp),
+		Body:    body.Bytes(),
+	}, KCacheTTLETag)
+
+	return nil
+}
+
+// fetchJSON fetches an ESI GET route and decodes the response into v, bypassing the response cache.
+// It serves routes whose responses never change and are cached by the caller, such as killmails.
+func (c *ESIClient) fetchJSON(ctx context.Context, path string, query url.Values, v interface{}) error {
+	req, err := c.newRequest(ctx, "GET", path, query, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make HTTP request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse("ESI", resp); err != nil {
+		return err
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to unmarshal JSON response: %w", err)
+	}
+
+	return nil
+}
+
+// responseExpires returns the time until which ESI will serve the same response.
+func responseExpires(resp *http.Response) time.Time {
+	expires, err := http.ParseTime(resp.Header.Get("Expires"))
+	if err != nil {
+		return time.Time{}
+	}
+	return expires
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestGetJSONRevalidatesWithETag(t *testing.T) {
+	calls := 0
+	expires := time.Now().Add(-time.Minute)
+	client := newFakeESI(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Expires", expires.UTC().Format(http.TimeFormat))
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		fmt.Fprint(w, `{"name": "Market Scammer"}`)
+	})
+
+	var character struct {
+		Name string `json:"name"`
+	}
+
+	// The first request fills the cache, the second one is revalidated and answered with 304.
+	for i := 0; i < 2; i++ {
+		character.Name = ""
+		if err := client.getJSON(context.Background(), "/characters/2117477599/", nil, &character); err != nil {
+			t.Errorf("Error occurred: %v", err)
+			return
+		}
+		if character.Name != "Market Scammer" {
+			t.Errorf("Expected the cached body to be reused, got: %v", character.Name)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("Expected 2 requests, got: %d", calls)
+	}
+
+	// Once ESI says the response is fresh, no request is made until it expires.
+	expires = time.Now().Add(time.Hour)
+	client.getJSON(context.Background(), "/characters/2117477599/", nil, &character)
+	client.getJSON(context.Background(), "/characters/2117477599/", nil, &character)
+	if calls != 3 {
+		t.Errorf("Expected the fresh response to be served from cache, got %d requests", calls)
+	}
+}
@@ -1,5 +1,56 @@
 package main
 
+import "time"
+
 const (
 	KUserAgent = "GoEye/0.1 Discord: iiiusi0n, In Game Name: Market Scammer"
+	// KAppID identifies the application to Fyne, which stores its preferences under it.
+	KAppID = "io.github.iiiusi0n.go-eye"
+
+	KESIBaseURL    = "https://esi.evetech.net/latest"
+	KESIDatasource = DatasourceTranquility
+
+	KZKillBaseURL = "https://zkillboard.com/api"
+
+	KHTTPTimeout     = 30 * time.Second
+	KHTTPDialTimeout = 10 * time.Second
+	// KHTTPProxy routes every request through the given proxy, e.g. "http://127.0.0.1:8080".
+	// When empty, the HTTP_PROXY and HTTPS_PROXY environment variables are used.
+	KHTTPProxy = ""
+
+	KESIMinInterval         = 50 * time.Millisecond
+	KZKillMinInterval       = 500 * time.Millisecond
+	KESIErrorLimitThreshold = 10
+
+	KKillmailWorkers = 4
+
+	// KRulesPollInterval is how often the rules file is checked for changes.
+	KRulesPollInterval = 2 * time.Second
+
+	// KYoungCharacterAge is the age under which a character is flagged as a likely alt.
+	KYoungCharacterAge = 30 * 24 * time.Hour
+
+	// A pilot joining KCorpHopperCount player corporations within KCorpHopperWindow, or leaving as many
+	// within KShortCorpTenure of joining, is flagged as a corp hopper.
+	KCorpHopperWindow = 365 * 24 * time.Hour
+	KCorpHopperCount  = 5
+	KShortCorpTenure  = 30 * 24 * time.Hour
+	// KRecentNPCExit is how long ago leaving an NPC corporation still raises a flag.
+	KRecentNPCExit    = 30 * 24 * time.Hour
+	KCorpHistoryShown = 10
+
+	KESINamesBatchSize   = 1000
+	KESIIDsBatchSize     = 500
+	KESIBatchConcurrency = 4
+
+	// Cache lifetimes, zero keeps an entry forever.
+	KCacheTTLKillmail  = 0
+	KCacheTTLTypeName  = 30 * 24 * time.Hour
+	KCacheTTLOtherName = 24 * time.Hour
+	KCacheTTLZKill     = 10 * time.Minute
+	KCacheTTLETag      = 7 * 24 * time.Hour
+
+	KMaxRetryAttempts = 3
+	KRetryBaseDelay   = 500 * time.Millisecond
+	KRetryMaxDelay    = 10 * time.Second
 )
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// corporationHistoryEntry is one record of /characters/{id}/corporationhistory/.
+type corporationHistoryEntry struct {
+	RecordID      int       `json:"record_id"`
+	CorporationID int       `json:"corporation_id"`
+	IsDeleted     bool      `json:"is_deleted,omitempty"`
+	StartDate     time.Time `json:"start_date"`
+}
+
+// CorporationStint is a period a character spent in one corporation.
+type CorporationStint struct {
+	CorporationID int
+	Name          string
+	Start         time.Time
+	// End is zero for the character's current corporation.
+	End     time.Time
+	Deleted bool
+}
+
+// NPC reports whether the stint was spent in an NPC corporation.
+func (s CorporationStint) NPC() bool {
+	return isNPCCorporation(s.CorporationID)
+}
+
+// Current reports whether the character is still in the corporation.
+func (s CorporationStint) Current() bool {
+	return s.End.IsZero()
+}
+
+// Tenure returns how long the character stayed, up to now for the current corporation.
+func (s CorporationStint) Tenure(now time.Time) time.Duration {
+	if s.Current() {
+		return now.Sub(s.Start)
+	}
+	return s.End.Sub(s.Start)
+}
+
+// isNPCCorporation reports whether a corporation ID belongs to the NPC corporation range.
+func isNPCCorporation(corporationID int) bool {
+	return corporationID >= 1000000 && corporationID < 2000000
+}
+
+// GetCorporationHistory fetches the corporations a character has been in, newest first.
+func GetCorporationHistory(ctx context.Context, characterID int) ([]CorporationStint, error) {
+	var entries []corporationHistoryEntry
+	err := esiClient.getJSON(ctx, fmt.Sprintf("/characters/%d/corporationhistory/", characterID), nil, &entries)
+	if err != nil {
+		return nil, err
+	}
+
+	stints := corporationStints(entries)
+
+	ids := make([]int, 0, len(stints))
+	for _, stint := range stints {
+		ids = append(ids, stint.CorporationID)
+	}
+	names, err := ResolveIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range stints {
+		if name := names[stints[i].CorporationID]; name.Resolved() {
+			stints[i].Name = name.Name
+		} else {
+			stints[i].Name = fmt.Sprintf("Corporation %d", stints[i].CorporationID)
+		}
+	}
+
+	return stints, nil
+}
+
+// corporationStints orders history records newest first and closes each stint when the next one starts.
+func corporationStints(entries []corporationHistoryEntry) []CorporationStint {
+	sorted := make([]corporationHistoryEntry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].RecordID > sorted[j].RecordID
+	})
+
+	stints := make([]CorporationStint, 0, len(sorted))
+	for i, entry := range sorted {
+		stint := CorporationStint{
+			CorporationID: entry.CorporationID,
+			Start:         entry.StartDate,
+			Deleted:       entry.IsDeleted,
+		}
+		if i > 0 {
+			stint.End = sorted[i-1].StartDate
+		}
+		stints = append(stints, stint)
+	}
+
+	return stints
+}
+
+// CorporationHistoryFlags returns the warnings raised by a corporation history, newest first.
+func CorporationHistoryFlags(stints []CorporationStint, now time.Time) []string {
+	flags := make([]string, 0)
+	if len(stints) == 0 {
+		return flags
+	}
+
+	joined := 0
+	for _, stint := range stints {
+		if !stint.NPC() && now.Sub(stint.Start) <= KCorpHopperWindow {
+			joined++
+		}
+	}
+	if joined >= KCorpHopperCount {
+		flags = append(flags, fmt.Sprintf("Frequent corp hopper: joined %d player corporations in %s", joined, formatAge(KCorpHopperWindow)))
+	}
+
+	current := stints[0]
+	if len(stints) > 1 && !current.NPC() && stints[1].NPC() && current.Tenure(now) <= KRecentNPCExit {
+		flags = append(flags, fmt.Sprintf("Recently left NPC corporation %s, %s ago", stints[1].Name, formatAge(current.Tenure(now))))
+	}
+
+	short := 0
+	for _, stint := range stints {
+		if !stint.NPC() && !stint.Current() && stint.Tenure(now) < KShortCorpTenure {
+			short++
+		}
+	}
+	if short >= KCorpHopperCount {
+		flags = append(flags, fmt.Sprintf("Left %d player corporations within %s of joining", short, formatAge(KShortCorpTenure)))
+	}
+
+	return flags
+}
+
+// CorporationHistoryText renders the flags and the most recent stints shown under the profile.
+// NPC corporation stints are marked so they stand out.
+func CorporationHistoryText(stints []CorporationStint, now time.Time) string {
+	lines := CorporationHistoryFlags(stints, now)
+	for i := range lines {
+		lines[i] = "Warning: " + lines[i]
+	}
+
+	for i, stint := range stints {
+		if i == KCorpHistoryShown {
+			lines = append(lines, fmt.Sprintf("... and %d earlier corporations", len(stints)-i))
+			break
+		}
+
+		line := fmt.Sprintf("%s  %s (%s)", stint.Start.Format("2006-01-02"), stint.Name, formatAge(stint.Tenure(now)))
+		if stint.NPC() {
+			line = "[NPC] " + line
+		}
+		if stint.Deleted {
+			line += " closed"
+		}
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCorporationStints(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	stints := corporationStints([]corporationHistoryEntry{
+		{RecordID: 1, CorporationID: 1000167, StartDate: start},
+		{RecordID: 3, CorporationID: 98000002, StartDate: start.AddDate(0, 2, 0)},
+		{RecordID: 2, CorporationID: 98000001, StartDate: start.AddDate(0, 1, 0), IsDeleted: true},
+	})
+
+	if len(stints) != 3 || stints[0].CorporationID != 98000002 || stints[2].CorporationID != 1000167 {
+		t.Errorf("Unexpected stint order: %+v", stints)
+		return
+	}
+	if !stints[0].Current() || !stints[1].End.Equal(stints[0].Start) || !stints[1].Deleted {
+		t.Errorf("Unexpected stint bounds: %+v", stints)
+	}
+	if !stints[2].NPC() || stints[1].NPC() {
+		t.Errorf("Expected only the first corporation to be an NPC corporation")
+	}
+}
+
+func TestCorporationHistoryFlags(t *testing.T) {
+	now := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	recentNPC := []CorporationStint{
+		{CorporationID: 98000001, Name: "Player Corp", Start: now.AddDate(0, 0, -5)},
+		{CorporationID: 1000167, Name: "State War Academy", Start: now.AddDate(-2, 0, 0), End: now.AddDate(0, 0, -5)},
+	}
+	flags := CorporationHistoryFlags(recentNPC, now)
+	if len(flags) != 1 || !strings.Contains(flags[0], "Recently left NPC corporation State War Academy") {
+		t.Errorf("Unexpected flags: %v", flags)
+	}
+
+	hopper := []CorporationStint{{CorporationID: 98000010, Name: "Corp 10", Start: now.AddDate(0, 0, -3)}}
+	for i := 1; i <= KCorpHopperCount; i++ {
+		hopper = append(hopper, CorporationStint{
+			CorporationID: 98000010 + i,
+			Name:          "Corp",
+			Start:         now.AddDate(0, 0, -3-10*i),
+			End:           now.AddDate(0, 0, -3-10*(i-1)),
+		})
+	}
+	flags = CorporationHistoryFlags(hopper, now)
+	if len(flags) != 2 || !strings.HasPrefix(flags[0], "Frequent corp hopper") {
+		t.Errorf("Unexpected flags: %v", flags)
+	}
+
+	text := CorporationHistoryText(recentNPC, now)
+	if !strings.Contains(text, "Warning: Recently left") || !strings.Contains(text, "[NPC] 2021-06-01  State War Academy") {
+		t.Errorf("Unexpected history text: %v", text)
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DiskCache is a persistent Cache. Every change is appended to a JSON lines log,
+// and the log is compacted when the cache is opened. Values are JSON documents,
+// as stored by setCachedJSON, and are written to the log as they are.
+type DiskCache struct {
+	mu      sync.Mutex
+	path    string
+	file    *os.File
+	entries map[string]diskCacheEntry
+}
+
+type diskCacheEntry struct {
+	Key   string          `json:"k"`
+	Value json.RawMessage `json:"j,omitempty"`
+	// LegacyValue is the base64 encoded value written by earlier versions, read but no longer written.
+	LegacyValue []byte    `json:"v,omitempty"`
+	Expires     time.Time `json:"e,omitempty"`
+	Deleted     bool      `json:"d,omitempty"`
+}
+
+func (e diskCacheEntry) expired(now time.Time) bool {
+	return !e.Expires.IsZero() && now.After(e.Expires)
+}
+
+// DefaultCachePath returns the location of the cache inside the user config directory.
+func DefaultCachePath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "go-eye", "cache.jsonl"), nil
+}
+
+// OpenDiskCache loads the cache stored at path, creating it if needed.
+func OpenDiskCache(path string) (*DiskCache, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	cache := &DiskCache{
+		path:    path,
+		entries: make(map[string]diskCacheEntry),
+	}
+	if err := cache.load(); err != nil {
+		return nil, err
+	}
+	if err := cache.compact(); err != nil {
+		return nil, err
+	}
+
+	return cache, nil
+}
+
+// load replays the log, keeping the last live entry of every key.
+func (c *DiskCache) load() error {
+	file, err := os.Open(c.path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to open cache: %w", err)
+	}
+	defer file.Close()
+
+	now := time.Now()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry diskCacheEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// A torn write at the end of the log only loses that entry.
+			continue
+		}
+		if entry.Value == nil && entry.LegacyValue != nil {
+			if !json.Valid(entry.LegacyValue) {
+				continue
+			}
+			entry.Value, entry.LegacyValue = entry.LegacyValue, nil
+		}
+		if entry.Deleted || entry.expired(now) {
+			delete(c.entries, entry.Key)
+		} else {
+			c.entries[entry.Key] = entry
+		}
+	}
+
+	return scanner.Err()
+}
+
+// compact rewrites the log with only the live entries and reopens it for appending.
+func (c *DiskCache) compact() error {
+	tmpPath := c.path + ".tmp"
+	tmp, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to create cache: %w", err)
+	}
+
+	writer := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(writer)
+	for _, entry := range c.entries {
+		if err := encoder.Encode(entry); err != nil {
+			tmp.Close()
+			return fmt.Errorf("failed to write cache: %w", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	if err := os.Rename(tmpPath, c.path); err != nil {
+		return fmt.Errorf("failed to replace cache: %w", err)
+	}
+
+	c.file, err = os.OpenFile(c.path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open cache: %w", err)
+	}
+	return nil
+}
+
+// append writes a single entry to the end of the log.
+func (c *DiskCache) append(entry diskCacheEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	if _, err := c.file.Write(append(data, '\n')); err != nil {
+		fmt.Printf("Error occurred: failed to write cache: %v\n", err)
+	}
+}
+
+func (c *DiskCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if entry.expired(time.Now()) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return []byte(entry.Value), true
+}
+
+func (c *DiskCache) Set(key string, value []byte, ttl time.Duration) {
+	if !json.Valid(value) {
+		fmt.Printf("Error occurred: failed to write cache: value of %q is not JSON\n", key)
+		return
+	}
+
+	entry := diskCacheEntry{Key: key, Value: value}
+	if ttl > 0 {
+		entry.Expires = time.Now().Add(ttl)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = entry
+	c.append(entry)
+}
+
+func (c *DiskCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[key]; !ok {
+		return
+	}
+	delete(c.entries, key)
+	c.append(diskCacheEntry{Key: key, Deleted: true})
+}
+
+// Close closes the underlying log file.
+func (c *DiskCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.file.Close()
+}
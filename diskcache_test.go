@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiskCachePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.jsonl")
+
+	cache, err := OpenDiskCache(path)
+	if err != nil {
+		t.Errorf("Error occurred: %v", err)
+		return
+	}
+	setCachedJSON(cache, "killmail:1", Killmail{KillmailID: 1, SolarSystemID: 30002187}, 0)
+	setCachedJSON(cache, "stats:1", 42, time.Millisecond)
+	setCachedJSON(cache, "name:2", ResolvedName{ID: 2, Name: "Rifter"}, time.Hour)
+	cache.Delete("name:2")
+	cache.Close()
+
+	time.Sleep(5 * time.Millisecond)
+
+	cache, err = OpenDiskCache(path)
+	if err != nil {
+		t.Errorf("Error occurred: %v", err)
+		return
+	}
+	defer cache.Close()
+
+	var killmail Killmail
+	if !getCachedJSON(cache, "killmail:1", &killmail) || killmail.SolarSystemID != 30002187 {
+		t.Errorf("Expected the killmail to survive a restart, got: %+v", killmail)
+	}
+
+	var stats int
+	if getCachedJSON(cache, "stats:1", &stats) {
+		t.Errorf("Expected the expired entry to be gone, got: %v", stats)
+	}
+
+	if _, ok := cache.Get("name:2"); ok {
+		t.Errorf("Expected the deleted entry to be gone")
+	}
+}
+
+func TestDiskCacheStoresPlainJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.jsonl")
+
+	cache, err := OpenDiskCache(path)
+	if err != nil {
+		t.Errorf("Error occurred: %v", err)
+		return
+	}
+	setCachedJSON(cache, "esi:/characters/1/", esiCachedResponse{ETag: `"abc"`, Body: json.RawMessage(`{"name":"Pilot"}`)}, 0)
+	cache.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Errorf("Error occurred: %v", err)
+		return
+	}
+	if !strings.Contains(string(data), `"data":{"name":"Pilot"}`) {
+		t.Errorf("Expected the response body to be stored as plain JSON, got: %s", data)
+	}
+}
+
+func TestDiskCacheReadsLegacyEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.jsonl")
+	legacy := fmt.Sprintf(`{"k":"killmail:1","v":%q}`+"\n", base64.StdEncoding.EncodeToString([]byte(`{"killmail_id":1,"solar_system_id":30002187}`)))
+	if err := os.WriteFile(path, []byte(legacy), 0o644); err != nil {
+		t.Errorf("Error occurred: %v", err)
+		return
+	}
+
+	cache, err := OpenDiskCache(path)
+	if err != nil {
+		t.Errorf("Error occurred: %v", err)
+		return
+	}
+	defer cache.Close()
+
+	var killmail Killmail
+	if !getCachedJSON(cache, "killmail:1", &killmail) || killmail.SolarSystemID != 30002187 {
+		t.Errorf("Expected the legacy killmail to be read, got: %+v", killmail)
+	}
+}
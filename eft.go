@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// eftSlotOrder is the order EFT lists the fitted slots in.
+var eftSlotOrder = []Slot{SlotLow, SlotMid, SlotHigh, SlotRig, SlotSubsystem}
+
+// EFT renders the fit in the EFT text format understood by Pyfa and the in-game fitting window:
+// the fitted modules by slot with their loaded charges, then drones and fighters, then cargo.
+func (f Fit) EFT(names map[int]ResolvedName, fitName string) string {
+	lines := []string{fmt.Sprintf("[%s, %s]", typeName(names, f.ShipTypeID), fitName)}
+
+	for _, slot := range eftSlotOrder {
+		modules := make([]FitItem, 0)
+		charges := make(map[int]int)
+		for _, item := range f.InSlot(slot) {
+			if item.Charge {
+				charges[item.Flag] = item.TypeID
+			} else {
+				modules = append(modules, item)
+			}
+		}
+		if len(modules) == 0 {
+			continue
+		}
+
+		sort.SliceStable(modules, func(i, j int) bool {
+			return modules[i].Flag < modules[j].Flag
+		})
+
+		lines = append(lines, "")
+		for _, module := range modules {
+			line := typeName(names, module.TypeID)
+			if charge, ok := charges[module.Flag]; ok {
+				line += ", " + typeName(names, charge)
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	for _, slots := range [][]Slot{{SlotDroneBay, SlotFighterBay}, {SlotCargo}} {
+		stacks := f.stacks(slots...)
+		if len(stacks) == 0 {
+			continue
+		}
+
+		lines = append(lines, "", "")
+		for _, stack := range stacks {
+			lines = append(lines, fmt.Sprintf("%s x%d", typeName(names, stack.TypeID), stack.Quantity))
+		}
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// stacks merges the items in the given slots by type, in the order each type first appears.
+func (f Fit) stacks(slots ...Slot) []FitItem {
+	items := make([]FitItem, 0)
+	for _, slot := range slots {
+		items = append(items, f.InSlot(slot)...)
+	}
+	return stackItems(items)
+}
+
+// stackItems merges items by type, in the order each type first appears.
+func stackItems(items []FitItem) []FitItem {
+	stacks := make([]FitItem, 0)
+	index := make(map[int]int)
+	for _, item := range items {
+		if i, ok := index[item.TypeID]; ok {
+			stacks[i].Quantity += item.Quantity
+			continue
+		}
+		index[item.TypeID] = len(stacks)
+		stacks = append(stacks, item)
+	}
+	return stacks
+}
+
+// typeName returns the resolved name of a type, or a placeholder naming its ID.
+func typeName(names map[int]ResolvedName, typeID int) string {
+	if name, ok := names[typeID]; ok && name.Resolved() && name.Name != "" {
+		return name.Name
+	}
+	return fmt.Sprintf("Unknown type %d", typeID)
+}
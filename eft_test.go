@@ -0,0 +1,101 @@
+package main
+
+import "testing"
+
+func TestFitEFT(t *testing.T) {
+	killmail := Killmail{KillmailID: 42, Victim: Victim{ShipTypeID: 587, Items: []KillmailItem{
+		{ItemTypeID: 2873, Flag: 28, QuantityDestroyed: 1},
+		{ItemTypeID: 2873, Flag: 27, QuantityDropped: 1},
+		{ItemTypeID: 12608, Flag: 27, QuantityDestroyed: 80},
+		{ItemTypeID: 12608, Flag: 27, QuantityDropped: 40},
+		{ItemTypeID: 447, Flag: 19, QuantityDestroyed: 1},
+		{ItemTypeID: 2048, Flag: 11, QuantityDropped: 1},
+		{ItemTypeID: 31790, Flag: 92, QuantityDestroyed: 1},
+		{ItemTypeID: 2456, Flag: 87, QuantityDestroyed: 1},
+		{ItemTypeID: 2456, Flag: 87, QuantityDropped: 2},
+		{ItemTypeID: 12608, Flag: 5, QuantityDropped: 500},
+	}}}
+
+	names := map[int]ResolvedName{
+		587:   {ID: 587, Name: "Rifter", Category: CategoryInventoryType},
+		2873:  {ID: 2873, Name: "125mm Gatling AutoCannon II", Category: CategoryInventoryType},
+		12608: {ID: 12608, Name: "Hail S", Category: CategoryInventoryType},
+		447:   {ID: 447, Name: "Warp Scrambler I", Category: CategoryInventoryType},
+		2048:  {ID: 2048, Name: "Damage Control II", Category: CategoryInventoryType},
+		2456:  {ID: 2456, Name: "Hobgoblin II", Category: CategoryInventoryType},
+	}
+
+	expected := `[Rifter, Killmail 42]
+
+Damage Control II
+
+Warp Scrambler I
+
+125mm Gatling AutoCannon II, Hail S
+125mm Gatling AutoCannon II
+
+Unknown type 31790
+
+
+Hobgoblin II x3
+
+
+Hail S x500
+`
+
+	row := summarizeLoss(killmail, names)
+	if !row.Resolved() {
+		t.Errorf("Expected a summarized loss to be resolved")
+	}
+	if eft := row.FitEFT(); eft != expected {
+		t.Errorf("Expected fit:\n%v\ngot:\n%v", expected, eft)
+	}
+	if pendingLossRow(killmail).Resolved() {
+		t.Errorf("Expected a pending loss not to be resolved")
+	}
+}
+
+func TestFitEFTSingleCharge(t *testing.T) {
+	previous := staticData
+	SetStaticData(&StaticData{
+		Types: map[int]sdeType{
+			3244:  {Name: "Warp Disruptor II", GroupID: GroupWarpScrambler},
+			45010: {Name: "Focused Warp Disruption Script", GroupID: 1702},
+			3041:  {Name: "Small Focused Beam Laser II", GroupID: 53},
+			246:   {Name: "Multifrequency S", GroupID: 86},
+		},
+		Groups: map[int]sdeGroup{
+			GroupWarpScrambler: {Name: "Warp Scrambler", CategoryID: SDECategoryModule},
+			53:                 {Name: "Energy Weapon", CategoryID: SDECategoryModule},
+			1702:               {Name: "Warp Disruption Script", CategoryID: SDECategoryCharge},
+			86:                 {Name: "Frequency Crystal", CategoryID: SDECategoryCharge},
+		},
+	})
+	defer SetStaticData(previous)
+
+	// The charges are listed before their modules, with the same quantity.
+	killmail := Killmail{KillmailID: 7, Victim: Victim{ShipTypeID: 597, Items: []KillmailItem{
+		{ItemTypeID: 45010, Flag: 19, QuantityDestroyed: 1},
+		{ItemTypeID: 3244, Flag: 19, QuantityDestroyed: 1},
+		{ItemTypeID: 246, Flag: 27, QuantityDropped: 1},
+		{ItemTypeID: 3041, Flag: 27, QuantityDropped: 1},
+	}}}
+
+	names := map[int]ResolvedName{
+		597:   {ID: 597, Name: "Punisher", Category: CategoryInventoryType},
+		3244:  {ID: 3244, Name: "Warp Disruptor II", Category: CategoryInventoryType},
+		45010: {ID: 45010, Name: "Focused Warp Disruption Script", Category: CategoryInventoryType},
+		3041:  {ID: 3041, Name: "Small Focused Beam Laser II", Category: CategoryInventoryType},
+		246:   {ID: 246, Name: "Multifrequency S", Category: CategoryInventoryType},
+	}
+
+	expected := `[Punisher, Killmail 7]
+
+Warp Disruptor II, Focused Warp Disruption Script
+
+Small Focused Beam Laser II, Multifrequency S
+`
+	if eft := FitFromKillmail(killmail).EFT(names, "Killmail 7"); eft != expected {
+		t.Errorf("Expected fit:\n%v\ngot:\n%v", expected, eft)
+	}
+}
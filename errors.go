@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+var (
+	// ErrNotFound is returned when the requested entity does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrRateLimited is returned when ESI error limits or zKillboard throttling kicked in.
+	ErrRateLimited = errors.New("rate limited")
+	// ErrUpstreamDown is returned when the remote service is failing or unreachable.
+	ErrUpstreamDown = errors.New("upstream unavailable")
+	// ErrInvalidHash is returned when a killmail ID and hash pair is rejected.
+	ErrInvalidHash = errors.New("invalid killmail hash")
+	// ErrUnexpectedStatus is returned for any other non-successful response.
+	ErrUnexpectedStatus = errors.New("unexpected response status")
+)
+
+// APIError describes a non-successful response from ESI or zKillboard.
+type APIError struct {
+	Service    string
+	StatusCode int
+	Message    string
+	Err        error
+}
+
+func (e *APIError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("%s responded with %d: %v: %s", e.Service, e.StatusCode, e.Err, e.Message)
+	}
+	return fmt.Sprintf("%s responded with %d: %v", e.Service, e.StatusCode, e.Err)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// checkResponse returns an APIError when the response does not carry a successful status code.
+func checkResponse(service string, resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	apiErr := &APIError{
+		Service:    service,
+		StatusCode: resp.StatusCode,
+		Message:    readErrorMessage(resp.Body),
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		apiErr.Err = ErrNotFound
+	case resp.StatusCode == 420 || resp.StatusCode == http.StatusTooManyRequests:
+		apiErr.Err = ErrRateLimited
+	case resp.StatusCode == http.StatusUnprocessableEntity:
+		// ESI answers 422 when the killmail hash does not match the ID.
+		apiErr.Err = ErrInvalidHash
+	case resp.StatusCode >= 500:
+		apiErr.Err = ErrUpstreamDown
+	default:
+		apiErr.Err = ErrUnexpectedStatus
+	}
+
+	return apiErr
+}
+
+// readErrorMessage extracts the "error" field ESI puts in failed responses, falling back to the raw body.
+func readErrorMessage(body io.Reader) string {
+	data, err := ioutil.ReadAll(io.LimitReader(body, 1024))
+	if err != nil || len(data) == 0 {
+		return ""
+	}
+
+	var esiError struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(data, &esiError) == nil && esiError.Error != "" {
+		return esiError.Error
+	}
+
+	return string(data)
+}
+
+// describeError turns an API error into a short message suitable for the user.
+func describeError(err error) string {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return "Nothing was found, check the pilot name and try again."
+	case errors.Is(err, ErrRateLimited):
+		return "Too many requests were made, please wait a moment and try again."
+	case errors.Is(err, ErrUpstreamDown):
+		return "ESI or zKillboard is currently unavailable, please try again later."
+	case errors.Is(err, ErrInvalidHash):
+		return "The killmail could not be verified by ESI."
+	default:
+		return err.Error()
+	}
+}
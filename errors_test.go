@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckResponse(t *testing.T) {
+	tests := []struct {
+		status   int
+		body     string
+		expected error
+	}{
+		{404, `{"error": "Character not found"}`, ErrNotFound},
+		{420, `{"error": "This software has exceeded the error limit for ESI."}`, ErrRateLimited},
+		{429, ``, ErrRateLimited},
+		{422, `{"error": "Invalid killmail_id and/or killmail_hash"}`, ErrInvalidHash},
+		{502, `<html>Bad Gateway</html>`, ErrUpstreamDown},
+		{400, ``, ErrUnexpectedStatus},
+	}
+
+	for _, test := range tests {
+		recorder := httptest.NewRecorder()
+		recorder.WriteHeader(test.status)
+		fmt.Fprint(recorder, test.body)
+
+		err := checkResponse("ESI", recorder.Result())
+		if !errors.Is(err, test.expected) {
+			t.Errorf("Expected %v for status %d, got: %v", test.expected, test.status, err)
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	recorder.WriteHeader(http.StatusOK)
+	if err := checkResponse("ESI", recorder.Result()); err != nil {
+		t.Errorf("Error occurred: %v", err)
+	}
+}
+
+func TestFetchItemsInvalidHash(t *testing.T) {
+	client := newFakeESI(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		fmt.Fprint(w, `{"error": "Invalid killmail_id and/or killmail_hash"}`)
+	})
+
+	_, err := client.fetchKillmailFromAPI(context.Background(), 1, "bad")
+	if !errors.Is(err, ErrInvalidHash) {
+		t.Errorf("Expected %v, got: %v", ErrInvalidHash, err)
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Message != "Invalid killmail_id and/or killmail_hash" {
+		t.Errorf("Unexpected error details: %v", err)
+	}
+}
@@ -2,58 +2,220 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 )
 
-// idCache stores the mapping of IDs to names for caching purposes.
-var idCache = make(map[int]string)
+const (
+	DatasourceTranquility = "tranquility"
+	DatasourceSingularity = "singularity"
+)
+
+// ESIClient holds the settings used to talk to the EVE Swagger Interface.
+type ESIClient struct {
+	BaseURL    string
+	Datasource string
+	UserAgent  string
+	HTTPClient *http.Client
+	Limiter    *RateLimiter
+	Retry      RetryPolicy
+	// Cache holds GET responses for conditional requests, the shared cache is used when nil.
+	Cache Cache
+}
+
+// NewESIClient creates an ESIClient pointed at the live Tranquility server.
+func NewESIClient() *ESIClient {
+	return &ESIClient{
+		BaseURL:    KESIBaseURL,
+		Datasource: KESIDatasource,
+		UserAgent:  KUserAgent,
+		HTTPClient: sharedHTTPClient,
+		Limiter:    esiRateLimiter,
+		Retry:      DefaultRetryPolicy,
+	}
+}
 
-// nameCache stores the mapping of names to IDs for caching purposes.
-var nameCache = make(map[string]int)
+// esiClient is the client used by the package level resolvers.
+var esiClient = NewESIClient()
 
-// itemNameCache stores the mapping of names to IDs for caching purposes.
-var itemNameCache = make(map[string]int)
+// SetESIClient replaces the client used by the package level resolvers.
+func SetESIClient(client *ESIClient) {
+	esiClient = client
+}
 
-// killmailItemsCache stores the items from killmails in cache to avoid repeated API requests.
-var killmailItemsCache = make(map[int][]int)
-var killmailTimeCache = make(map[int]time.Time)
+// endpoint builds the full URL of an ESI route, adding the datasource to the query.
+func (c *ESIClient) endpoint(path string, query url.Values) string {
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set("datasource", c.Datasource)
+	return strings.TrimRight(c.BaseURL, "/") + path + "?" + query.Encode()
+}
 
-// ResolveIdsToNames resolves a list of IDs to their corresponding names using the cache and EVE Online API.
-func ResolveIdsToNames(ids []int) ([]string, error) {
-	names, unresolvedIds, err := getNamesFromCache(ids)
+// newRequest creates an HTTP request for an ESI route with the common headers set.
+func (c *ESIClient) newRequest(ctx context.Context, method string, path string, query url.Values, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoint(path, query), body)
 	if err != nil {
 		return nil, err
 	}
+	req.Header.Add("accept", "application/json")
+	if body != nil {
+		req.Header.Add("Content-Type", "application/json")
+	}
+	req.Header.Add("User-Agent", c.UserAgent)
+	return req, nil
+}
+
+// do sends the request through the client's rate limiter and retry policy.
+func (c *ESIClient) do(req *http.Request) (*http.Response, error) {
+	return doWithRetry(c.HTTPClient, c.Limiter, c.Retry, req)
+}
+
+const (
+	CategoryCharacter     = "character"
+	CategoryCorporation   = "corporation"
+	CategoryAlliance      = "alliance"
+	CategoryInventoryType = "inventory_type"
+	CategorySolarSystem   = "solar_system"
+	CategoryFaction       = "faction"
+	CategoryUnresolved    = "unresolved"
+)
+
+// ResolvedName is the result of resolving a single ID through ESI.
+type ResolvedName struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Category string `json:"category"`
+}
+
+// Resolved reports whether ESI knew the ID.
+func (r ResolvedName) Resolved() bool {
+	return r.Category != CategoryUnresolved
+}
+
+// nameTTL returns how long a resolved name stays in the cache. Type names
+// practically never change, while characters, corporations and alliances can be renamed.
+// IDs ESI rejected are remembered as unresolved for as long as the latter.
+func nameTTL(category string) time.Duration {
+	if category == CategoryInventoryType || category == CategorySolarSystem {
+		return KCacheTTLTypeName
+	}
+	return KCacheTTLOtherName
+}
+
+// cachedName looks up a resolved name in the cache.
+func cachedName(id int) (ResolvedName, bool) {
+	var name ResolvedName
+	ok := getCachedJSON(sharedCache, fmt.Sprintf("name:%d", id), &name)
+	return name, ok
+}
+
+// cacheName stores a resolved name in the cache.
+func cacheName(name ResolvedName) {
+	setCachedJSON(sharedCache, fmt.Sprintf("name:%d", name.ID), name, nameTTL(name.Category))
+	if name.Category == CategoryCharacter {
+		setCachedJSON(sharedCache, "character:"+name.Name, name.ID, KCacheTTLOtherName)
+	}
+}
+
+// cachedCharacterID looks up a character ID by name in the cache.
+func cachedCharacterID(name string) (int, bool) {
+	var id int
+	ok := getCachedJSON(sharedCache, "character:"+name, &id)
+	return id, ok
+}
+
+// cachedItemID looks up an inventory type ID by name in the cache.
+func cachedItemID(name string) (int, bool) {
+	var id int
+	ok := getCachedJSON(sharedCache, "item:"+name, &id)
+	return id, ok
+}
+
+// cacheItemID stores an inventory type ID in the cache.
+func cacheItemID(name string, id int) {
+	setCachedJSON(sharedCache, "item:"+name, id, KCacheTTLTypeName)
+}
+
+// ResolveIDs resolves a list of IDs using the cache and EVE Online API. IDs unknown to ESI are left out of the map.
+func ResolveIDs(ctx context.Context, ids []int) (map[int]ResolvedName, error) {
+	resolved, unresolvedIds := getNamesFromCache(ids)
 
 	if len(unresolvedIds) > 0 {
-		newNames, err := resolveNamesFromAPI(unresolvedIds)
+		newNames, err := esiClient.resolveNamesFromAPI(ctx, unresolvedIds)
 		if err != nil {
 			return nil, err
 		}
-		names = append(names, newNames...)
+		for _, name := range newNames {
+			if name.Resolved() {
+				resolved[name.ID] = name
+			}
+		}
+	}
+
+	return resolved, nil
+}
+
+// ResolveIDsOrdered resolves a list of IDs and returns exactly one entry per input, in input order.
+// IDs unknown to ESI are returned with the CategoryUnresolved category.
+func ResolveIDsOrdered(ctx context.Context, ids []int) ([]ResolvedName, error) {
+	resolved, err := ResolveIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]ResolvedName, len(ids))
+	for i, id := range ids {
+		if name, ok := resolved[id]; ok {
+			result[i] = name
+		} else {
+			result[i] = ResolvedName{ID: id, Category: CategoryUnresolved}
+		}
+	}
+
+	return result, nil
+}
+
+// ResolveIdsToNames resolves a list of IDs to their corresponding names, one per input ID and in the same order.
+// IDs unknown to ESI resolve to an empty name.
+func ResolveIdsToNames(ctx context.Context, ids []int) ([]string, error) {
+	resolved, err := ResolveIDsOrdered(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(resolved))
+	for i, name := range resolved {
+		names[i] = name.Name
 	}
 
 	return names, nil
 }
 
-// getNamesFromCache retrieves names from the cache and returns unresolved IDs.
-func getNamesFromCache(ids []int) ([]string, []int, error) {
-	names := make([]string, 0)
+// getNamesFromCache retrieves resolved names from the cache and returns the IDs missing from it.
+// IDs cached as rejected by ESI are neither returned as names nor as missing.
+func getNamesFromCache(ids []int) (map[int]ResolvedName, []int) {
+	resolved := make(map[int]ResolvedName)
 	unresolvedIds := make([]int, 0)
 
 	for _, id := range ids {
-		if name, ok := idCache[id]; ok {
-			names = append(names, name)
+		if name, ok := cachedName(id); ok {
+			if name.Resolved() {
+				resolved[id] = name
+			}
 		} else {
 			unresolvedIds = append(unresolvedIds, id)
 		}
 	}
 
-	return names, unresolvedIds, nil
+	return resolved, unique(unresolvedIds)
 }
 
 func unique(intSlice []int) []int {
@@ -69,51 +231,80 @@ func unique(intSlice []int) []int {
 }
 
 // resolveNamesFromAPI resolves a list of unresolved IDs to names using EVE Online API.
-func resolveNamesFromAPI(ids []int) ([]string, error) {
-	ids = unique(ids)
+// The IDs are sent in batches no larger than ESI accepts and the results are merged.
+// IDs ESI rejected are returned and cached as unresolved, so later lookups skip them.
+func (c *ESIClient) resolveNamesFromAPI(ctx context.Context, ids []int) ([]ResolvedName, error) {
+	newNames, err := runBatches(unique(ids), KESINamesBatchSize, func(batch []int) ([]ResolvedName, error) {
+		return c.resolveNameBatch(ctx, batch)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range newNames {
+		cacheName(entry)
+	}
+
+	return newNames, nil
+}
+
+// resolveNameBatch resolves a single batch of IDs. ESI rejects the whole request
+// when any ID is invalid, so a rejected batch is split to isolate the invalid IDs,
+// which are returned as unresolved. Every rejection costs a request from the ESI
+// error budget, so splitting stops with ErrRateLimited while the budget is low.
+func (c *ESIClient) resolveNameBatch(ctx context.Context, ids []int) ([]ResolvedName, error) {
+	newNames, err := c.postNames(ctx, ids)
+	if errors.Is(err, ErrNotFound) {
+		if len(ids) == 1 {
+			return []ResolvedName{{ID: ids[0], Category: CategoryUnresolved}}, nil
+		}
+		if c.Limiter.ErrorBudgetLow() {
+			return nil, fmt.Errorf("isolating invalid IDs among %d: %w", len(ids), ErrRateLimited)
+		}
+
+		left, err := c.resolveNameBatch(ctx, ids[:len(ids)/2])
+		if err != nil {
+			return nil, err
+		}
+		right, err := c.resolveNameBatch(ctx, ids[len(ids)/2:])
+		if err != nil {
+			return nil, err
+		}
+		return append(left, right...), nil
+	}
+
+	return newNames, err
+}
+
+// postNames sends a single /universe/names/ request.
+func (c *ESIClient) postNames(ctx context.Context, ids []int) ([]ResolvedName, error) {
 	body, err := json.Marshal(ids)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal unresolved IDs: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", "https://esi.evetech.net/latest/universe/names/?datasource=tranquility", bytes.NewReader(body))
+	req, err := c.newRequest(ctx, "POST", "/universe/names/", nil, bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
 	}
-	req.Header.Add("accept", "application/json")
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("User-Agent", KUserAgent)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to make HTTP request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	dataA, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read HTTP response body: %w", err)
-	}
-
-	var data []struct {
-		ID   int    `json:"id"`
-		Name string `json:"name"`
+	if err := checkResponse("ESI", resp); err != nil {
+		return nil, err
 	}
 
-	err = json.Unmarshal(dataA, &data)
+	var data []ResolvedName
+	err = json.NewDecoder(resp.Body).Decode(&data)
 	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal JSON response: %w", err)
 	}
 
-	newNames := make([]string, 0)
-	for _, entry := range data {
-		idCache[entry.ID] = entry.Name
-		nameCache[entry.Name] = entry.ID
-		newNames = append(newNames, entry.Name)
-	}
-
-	return newNames, nil
+	return data, nil
 }
 
 // characterInfo holds character ID and name information.
@@ -122,15 +313,21 @@ type characterInfo struct {
 	Name string `json:"name"`
 }
 
+// itemInfo holds inventory type ID and name information.
+type itemInfo struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
 // ResolveNamesToCharacterIDs resolves a list of names to their corresponding character IDs using the cache and EVE Online API.
-func ResolveNamesToCharacterIDs(names []string) ([]int, error) {
+func ResolveNamesToCharacterIDs(ctx context.Context, names []string) ([]int, error) {
 	ids, unresolvedNames, err := getIDsFromCache(names)
 	if err != nil {
 		return nil, err
 	}
 
 	if len(unresolvedNames) > 0 {
-		newIDs, err := resolveIDsFromAPI(unresolvedNames)
+		newIDs, err := esiClient.resolveIDsFromAPI(ctx, unresolvedNames)
 		if err != nil {
 			return nil, err
 		}
@@ -140,14 +337,14 @@ func ResolveNamesToCharacterIDs(names []string) ([]int, error) {
 	return ids, nil
 }
 
-func ResolveItemNamesToIDs(names []string) ([]int, error) {
+func ResolveItemNamesToIDs(ctx context.Context, names []string) ([]int, error) {
 	ids, unresolvedNames, err := getItemIDsFromCache(names)
 	if err != nil {
 		return nil, err
 	}
 
 	if len(unresolvedNames) > 0 {
-		newIDs, err := resolveItemIDsFromAPI(unresolvedNames)
+		newIDs, err := esiClient.resolveItemIDsFromAPI(ctx, unresolvedNames)
 		if err != nil {
 			fmt.Println("Error resolving item names to IDs: ", err)
 			return nil, err
@@ -163,7 +360,7 @@ func getItemIDsFromCache(names []string) ([]int, []string, error) {
 	unresolvedNames := make([]string, 0)
 
 	for _, name := range names {
-		if id, ok := itemNameCache[name]; ok {
+		if id, ok := cachedItemID(name); ok {
 			ids = append(ids, id)
 		} else {
 			unresolvedNames = append(unresolvedNames, name)
@@ -173,47 +370,18 @@ func getItemIDsFromCache(names []string) ([]int, []string, error) {
 	return ids, unresolvedNames, nil
 }
 
-func resolveItemIDsFromAPI(names []string) ([]int, error) {
-	body, err := json.Marshal(names)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal unresolved names: %w", err)
-	}
-
-	req, err := http.NewRequest("POST", "https://esi.evetech.net/latest/universe/ids/?datasource=tranquility", bytes.NewReader(body))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
-	}
-	req.Header.Add("accept", "application/json")
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("User-Agent", KUserAgent)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
+func (c *ESIClient) resolveItemIDsFromAPI(ctx context.Context, names []string) ([]int, error) {
+	items, err := runBatches(names, KESIIDsBatchSize, func(batch []string) ([]itemInfo, error) {
+		response, err := c.postIDs(ctx, batch, nil)
+		return response.InventoryTypes, err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to make HTTP request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	dataA, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read HTTP response body: %w", err)
-	}
-
-	var response struct {
-		Items []struct {
-			ID   int    `json:"id"`
-			Name string `json:"name"`
-		} `json:"inventory_types"`
-	}
-
-	err = json.Unmarshal(dataA, &response)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal JSON response: %w", err)
+		return nil, err
 	}
 
 	newIDs := make([]int, 0)
-	for _, entry := range response.Items {
-		itemNameCache[entry.Name] = entry.ID
+	for _, entry := range items {
+		cacheItemID(entry.Name, entry.ID)
 		newIDs = append(newIDs, entry.ID)
 	}
 
@@ -226,7 +394,7 @@ func getIDsFromCache(names []string) ([]int, []string, error) {
 	unresolvedNames := make([]string, 0)
 
 	for _, name := range names {
-		if id, ok := nameCache[name]; ok {
+		if id, ok := cachedCharacterID(name); ok {
 			ids = append(ids, id)
 		} else {
 			unresolvedNames = append(unresolvedNames, name)
@@ -237,115 +405,115 @@ func getIDsFromCache(names []string) ([]int, []string, error) {
 }
 
 // resolveIDsFromAPI resolves a list of unresolved names to character IDs using EVE Online API.
-func resolveIDsFromAPI(names []string) ([]int, error) {
-	body, err := json.Marshal(names)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal unresolved names: %w", err)
-	}
-
-	req, err := http.NewRequest("POST", "https://esi.evetech.net/latest/universe/ids/?datasource=tranquility&language=en", bytes.NewReader(body))
+// The names are sent in batches no larger than ESI accepts and the results are merged.
+func (c *ESIClient) resolveIDsFromAPI(ctx context.Context, names []string) ([]int, error) {
+	characters, err := runBatches(names, KESIIDsBatchSize, func(batch []string) ([]characterInfo, error) {
+		response, err := c.postIDs(ctx, batch, url.Values{"language": {"en"}})
+		return response.Characters, err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
-	}
-	req.Header.Add("accept", "application/json")
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("User-Agent", KUserAgent)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to make HTTP request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	dataA, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read HTTP response body: %w", err)
-	}
-
-	var response struct {
-		Characters []characterInfo `json:"characters"`
-	}
-
-	err = json.Unmarshal(dataA, &response)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal JSON response: %w", err)
+		return nil, err
 	}
 
 	newIDs := make([]int, 0)
-	for _, entry := range response.Characters {
-		idCache[entry.ID] = entry.Name
-		nameCache[entry.Name] = entry.ID
+	for _, entry := range characters {
+		cacheName(ResolvedName{ID: entry.ID, Name: entry.Name, Category: CategoryCharacter})
 		newIDs = append(newIDs, entry.ID)
 	}
 
 	return newIDs, nil
 }
 
-// fetchItemsFromAPI makes an API request and retrieves items from a killmail.
-func fetchItemsFromAPI(id int, hash string) ([]int, time.Time, error) {
-	req, err := http.NewRequest("GET", fmt.Sprintf("https://esi.evetech.net/latest/killmails/%d/%s/?datasource=tranquility", id, hash), nil)
+// idsResponse holds the parts of the /universe/ids/ response go-eye uses.
+type idsResponse struct {
+	Characters     []characterInfo `json:"characters"`
+	InventoryTypes []itemInfo      `json:"inventory_types"`
+}
+
+// postIDs sends a single /universe/ids/ request.
+func (c *ESIClient) postIDs(ctx context.Context, names []string, query url.Values) (idsResponse, error) {
+	body, err := json.Marshal(names)
 	if err != nil {
-		return nil, time.Time{}, err
+		return idsResponse{}, fmt.Errorf("failed to marshal unresolved names: %w", err)
 	}
-	req.Header.Add("accept", "application/json")
-	req.Header.Add("User-Agent", KUserAgent)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	req, err := c.newRequest(ctx, "POST", "/universe/ids/", query, bytes.NewReader(body))
 	if err != nil {
-		return nil, time.Time{}, err
+		return idsResponse{}, fmt.Errorf("failed to create HTTP request: %w", err)
 	}
-	defer resp.Body.Close()
 
-	dataA, err := ioutil.ReadAll(resp.Body)
+	resp, err := c.do(req)
 	if err != nil {
-		return nil, time.Time{}, err
+		return idsResponse{}, fmt.Errorf("failed to make HTTP request: %w", err)
 	}
+	defer resp.Body.Close()
 
-	var data struct {
-		Victim struct {
-			Items []struct {
-				ItemType int `json:"item_type_id"`
-			} `json:"items"`
-		} `json:"victim"`
-		KillmailTime string `json:"killmail_time"`
+	if err := checkResponse("ESI", resp); err != nil {
+		return idsResponse{}, err
 	}
 
-	err = json.Unmarshal(dataA, &data)
+	var response idsResponse
+	err = json.NewDecoder(resp.Body).Decode(&response)
 	if err != nil {
-		return nil, time.Time{}, err
+		return idsResponse{}, fmt.Errorf("failed to unmarshal JSON response: %w", err)
 	}
 
-	items := make([]int, 0)
-	for _, item := range data.Victim.Items {
-		items = append(items, item.ItemType)
-	}
+	return response, nil
+}
 
-	killmailTime, err := time.Parse("2006-01-02T15:04:05Z", data.KillmailTime)
+// fetchKillmailFromAPI makes an API request and retrieves a killmail.
+func (c *ESIClient) fetchKillmailFromAPI(ctx context.Context, id int, hash string) (Killmail, error) {
+	var killmail Killmail
+	err := c.fetchJSON(ctx, fmt.Sprintf("/killmails/%d/%s/", id, hash), nil, &killmail)
 	if err != nil {
-		return nil, time.Time{}, err
+		return Killmail{}, err
 	}
 
-	return items, killmailTime, nil
+	return killmail, nil
 }
 
-// GetItemsFromKillmail retrieves items from a killmail with caching support.
-func GetItemsFromKillmail(id int, hash string) ([]int, time.Time, error) {
+// GetKillmail retrieves a killmail with caching support. Killmails never change once published.
+// Concurrent calls for the same killmail share a single request.
+func GetKillmail(ctx context.Context, id int, hash string) (Killmail, error) {
+	key := fmt.Sprintf("killmail:%d", id)
+
 	// Check if the data is already in the cache
-	if cachedItems, ok := killmailItemsCache[id]; ok {
-		return cachedItems, killmailTimeCache[id], nil
+	var cached Killmail
+	if getCachedJSON(sharedCache, key, &cached) {
+		return cached, nil
 	}
 
-	// Fetch items from the API
-	items, t, err := fetchItemsFromAPI(id, hash)
+	return doShared(ctx, requests, key, func(ctx context.Context) (Killmail, error) {
+		// Another caller may have just finished the same request
+		var cached Killmail
+		if getCachedJSON(sharedCache, key, &cached) {
+			return cached, nil
+		}
+
+		// Fetch the killmail from the API
+		killmail, err := esiClient.fetchKillmailFromAPI(ctx, id, hash)
+		if err != nil {
+			return Killmail{}, err
+		}
+
+		// Cache the data for future use
+		setCachedJSON(sharedCache, key, killmail, KCacheTTLKillmail)
+
+		return killmail, nil
+	})
+}
+
+// GetItemsFromKillmail retrieves the type IDs of the victim's top level items and the time of a killmail.
+func GetItemsFromKillmail(ctx context.Context, id int, hash string) ([]int, time.Time, error) {
+	killmail, err := GetKillmail(ctx, id, hash)
 	if err != nil {
 		return nil, time.Time{}, err
 	}
 
-	// Cache the data for future use
-	killmailItemsCache[id] = items
-	killmailTimeCache[id] = t
+	items := make([]int, 0)
+	for _, item := range killmail.Victim.Items {
+		items = append(items, item.ItemTypeID)
+	}
 
-	return items, t, nil
+	return items, killmail.KillmailTime, nil
 }
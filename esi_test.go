@@ -1,85 +1,329 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"sync/atomic"
 	"testing"
 )
 
-func TestResolveIdsToNames(t *testing.T) {
-	ids := []int{29990, 602}
-	expectedNames := []string{"Loki", "Kestrel"}
+func TestESIClientBaseURLAndDatasource(t *testing.T) {
+	client := newFakeESI(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/universe/names/" {
+			t.Errorf("Unexpected path: %v", r.URL.Path)
+		}
+		if r.URL.Query().Get("datasource") != DatasourceSingularity {
+			t.Errorf("Expected datasource %v, got: %v", DatasourceSingularity, r.URL.Query().Get("datasource"))
+		}
+		if r.Header.Get("User-Agent") != "go-eye test" {
+			t.Errorf("Unexpected User-Agent: %v", r.Header.Get("User-Agent"))
+		}
+		json.NewEncoder(w).Encode([]map[string]interface{}{
+			{"id": 900000001, "name": "Test Frigate", "category": "inventory_type"},
+		})
+	})
+	client.Datasource = DatasourceSingularity
+	client.UserAgent = "go-eye test"
 
-	names, err := ResolveIdsToNames(ids)
+	names, err := client.resolveNamesFromAPI(context.Background(), []int{900000001})
 	if err != nil {
 		t.Errorf("Error occurred: %v", err)
 		return
 	}
 
+	expectedNames := []ResolvedName{{ID: 900000001, Name: "Test Frigate", Category: CategoryInventoryType}}
 	if !reflect.DeepEqual(names, expectedNames) {
 		t.Errorf("Expected names: %v, got: %v", expectedNames, names)
 	}
+}
+
+// newFakeESI starts a stand-in ESI server answering with handler and points the resolvers at it for the
+// rest of the test. It returns the client in use, so tests can adjust it or call its methods directly.
+func newFakeESI(t *testing.T, handler http.HandlerFunc) *ESIClient {
+	server := httptest.NewServer(handler)
+
+	client := NewESIClient()
+	client.BaseURL = server.URL
+	client.HTTPClient = server.Client()
+	client.Limiter = nil
+	client.Cache = NewMemoryCache()
 
-	t.Logf("Names: %v", names)
+	previous := esiClient
+	SetESIClient(client)
+	t.Cleanup(func() {
+		SetESIClient(previous)
+		server.Close()
+	})
+
+	return client
 }
 
-func TestResolveNamesToCharacterIDs(t *testing.T) {
-	names := []string{"Market Scammer", "Market Trickster"}
-	expectedIDs := []int{2117477599, 2118503862}
+// newFakeNamesESI starts a stand-in ESI server that knows the given names and rejects batches with unknown IDs like ESI does.
+// It returns the number of requests the server received.
+func newFakeNamesESI(t *testing.T, known map[int]ResolvedName) *int32 {
+	var calls int32
+	newFakeESI(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		var ids []int
+		if err := json.NewDecoder(r.Body).Decode(&ids); err != nil {
+			t.Errorf("Error occurred: %v", err)
+		}
+		if len(ids) > KESINamesBatchSize {
+			t.Errorf("Request holds %d IDs, ESI accepts at most %d", len(ids), KESINamesBatchSize)
+		}
+
+		result := make([]ResolvedName, 0)
+		for _, id := range ids {
+			name, ok := known[id]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(map[string]string{"error": "Ensure all IDs are valid before resolving."})
+				return
+			}
+			result = append(result, name)
+		}
+		json.NewEncoder(w).Encode(result)
+	})
 
-	ids, err := ResolveNamesToCharacterIDs(names)
+	return &calls
+}
+
+func TestResolveIDsOrdered(t *testing.T) {
+	newFakeNamesESI(t, map[int]ResolvedName{
+		910000001: {ID: 910000001, Name: "Fake Scrambler", Category: CategoryInventoryType},
+		910000002: {ID: 910000002, Name: "Fake Pilot", Category: CategoryCharacter},
+	})
+
+	ids := []int{910000002, 910000003, 910000001, 910000002}
+	expected := []ResolvedName{
+		{ID: 910000002, Name: "Fake Pilot", Category: CategoryCharacter},
+		{ID: 910000003, Category: CategoryUnresolved},
+		{ID: 910000001, Name: "Fake Scrambler", Category: CategoryInventoryType},
+		{ID: 910000002, Name: "Fake Pilot", Category: CategoryCharacter},
+	}
+
+	resolved, err := ResolveIDsOrdered(context.Background(), ids)
 	if err != nil {
 		t.Errorf("Error occurred: %v", err)
 		return
 	}
 
-	if !reflect.DeepEqual(ids, expectedIDs) {
-		t.Errorf("Expected IDs: %v, got: %v", expectedIDs, ids)
+	if !reflect.DeepEqual(resolved, expected) {
+		t.Errorf("Expected names: %v, got: %v", expected, resolved)
 	}
 
-	t.Logf("IDs: %v", ids)
+	names, err := ResolveIDs(context.Background(), ids)
+	if err != nil {
+		t.Errorf("Error occurred: %v", err)
+		return
+	}
+
+	if len(names) != 2 || names[910000001].Name != "Fake Scrambler" {
+		t.Errorf("Unexpected names: %v", names)
+	}
 }
 
-func TestGetItemsFromKillmail(t *testing.T) {
-	killmailID := 97332126
-	killmailHash := "1627401de883aa21f99c8618e5b8ca59f7904dae"
-	expectedItems := []int{16650, 3828, 11399, 2393, 32880, 3683, 1319, 1319, 16644, 16648, 16273, 1319, 16647, 16649, 29984, 16274, 16638, 16641, 16651}
+func TestResolveIDsInBatches(t *testing.T) {
+	known := make(map[int]ResolvedName)
+	ids := make([]int, 0)
+	for id := 920000000; id < 920002500; id++ {
+		known[id] = ResolvedName{ID: id, Name: fmt.Sprintf("Pilot %d", id), Category: CategoryCharacter}
+		ids = append(ids, id)
+	}
+	newFakeNamesESI(t, known)
 
-	items, _, err := GetItemsFromKillmail(killmailID, killmailHash)
+	resolved, err := ResolveIDsOrdered(context.Background(), ids)
 	if err != nil {
 		t.Errorf("Error occurred: %v", err)
 		return
 	}
 
-	if !reflect.DeepEqual(items, expectedItems) {
-		t.Errorf("Expected items: %v, got: %v", expectedItems, items)
+	for i, name := range resolved {
+		if name != known[ids[i]] {
+			t.Errorf("Expected %v at %d, got: %v", known[ids[i]], i, name)
+			return
+		}
+	}
+}
+
+func TestResolveIDsRemembersRejectedIDs(t *testing.T) {
+	previous := sharedCache
+	SetCache(NewMemoryCache())
+	defer SetCache(previous)
+
+	known := make(map[int]ResolvedName)
+	ids := make([]int, 0)
+	for id := 925000000; id < 925000016; id++ {
+		known[id] = ResolvedName{ID: id, Name: fmt.Sprintf("Pilot %d", id), Category: CategoryCharacter}
+		ids = append(ids, id)
+	}
+	ids = append(ids, 925999999)
+	calls := newFakeNamesESI(t, known)
+
+	for i := 0; i < 2; i++ {
+		names, err := ResolveIDs(context.Background(), ids)
+		if err != nil {
+			t.Errorf("Error occurred: %v", err)
+			return
+		}
+		if _, ok := names[925999999]; ok || len(names) != len(known) {
+			t.Errorf("Expected only the known IDs, got: %v", names)
+		}
 	}
 
-	t.Logf("Items: %v", items)
+	first := atomic.LoadInt32(calls)
+	if first < 2 {
+		t.Errorf("Expected the rejected batch to be split, got %d requests", first)
+	}
+
+	// A new batch holding the rejected ID must not be rejected again.
+	names, err := ResolveIDs(context.Background(), []int{925999999, 925000020})
+	if err != nil {
+		t.Errorf("Error occurred: %v", err)
+		return
+	}
+	if len(names) != 0 || atomic.LoadInt32(calls) != first+1 {
+		t.Errorf("Expected a single request for the new ID, got %d requests and names: %v", atomic.LoadInt32(calls)-first, names)
+	}
 }
 
-func TestGetItemsFromKillmailCaching(t *testing.T) {
-	killmailID := 97332126
-	killmailHash := "1627401de883aa21f99c8618e5b8ca59f7904dae"
-	expectedItems := []int{16650, 3828, 11399, 2393, 32880, 3683, 1319, 1319, 16644, 16648, 16273, 1319, 16647, 16649, 29984, 16274, 16638, 16641, 16651}
+func TestResolveNameBatchStopsSplittingOnLowErrorBudget(t *testing.T) {
+	var calls int32
+	client := newFakeESI(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("X-ESI-Error-Limit-Remain", "5")
+		w.Header().Set("X-ESI-Error-Limit-Reset", "30")
+		w.WriteHeader(http.StatusNotFound)
+	})
+	client.Limiter = NewRateLimiter(0)
+
+	names, err := client.resolveNameBatch(context.Background(), []int{926000001, 926000002, 926000003, 926000004})
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected a rate limit error, got: %v", err)
+	}
 
-	items, _, err := GetItemsFromKillmail(killmailID, killmailHash)
+	if len(names) != 0 || calls != 1 {
+		t.Errorf("Expected one request and no names, got %d requests and names: %v", calls, names)
+	}
+}
+
+func TestResolveIdsToNames(t *testing.T) {
+	previous := sharedCache
+	SetCache(NewMemoryCache())
+	defer SetCache(previous)
+
+	newFakeNamesESI(t, map[int]ResolvedName{
+		29990: {ID: 29990, Name: "Loki", Category: CategoryInventoryType},
+		602:   {ID: 602, Name: "Kestrel", Category: CategoryInventoryType},
+	})
+
+	ids := []int{29990, 602}
+	expectedNames := []string{"Loki", "Kestrel"}
+
+	names, err := ResolveIdsToNames(context.Background(), ids)
 	if err != nil {
 		t.Errorf("Error occurred: %v", err)
 		return
 	}
 
-	if !reflect.DeepEqual(items, expectedItems) {
-		t.Errorf("Expected items: %v, got: %v", expectedItems, items)
+	if !reflect.DeepEqual(names, expectedNames) {
+		t.Errorf("Expected names: %v, got: %v", expectedNames, names)
 	}
+}
+
+// newFakeIDsESI starts a stand-in ESI server answering /universe/ids/ with the given characters and inventory types.
+func newFakeIDsESI(t *testing.T, characters map[string]int, types map[string]int) {
+	newFakeESI(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/universe/ids/" {
+			t.Errorf("Unexpected path: %v", r.URL.Path)
+		}
+		var names []string
+		if err := json.NewDecoder(r.Body).Decode(&names); err != nil {
+			t.Errorf("Error occurred: %v", err)
+		}
 
-	t.Logf("Items: %v", items)
+		var response idsResponse
+		for _, name := range names {
+			if id, ok := characters[name]; ok {
+				response.Characters = append(response.Characters, characterInfo{ID: id, Name: name})
+			}
+			if id, ok := types[name]; ok {
+				response.InventoryTypes = append(response.InventoryTypes, itemInfo{ID: id, Name: name})
+			}
+		}
+		json.NewEncoder(w).Encode(response)
+	})
+}
+
+func TestResolveNamesToCharacterIDs(t *testing.T) {
+	previous := sharedCache
+	SetCache(NewMemoryCache())
+	defer SetCache(previous)
+
+	newFakeIDsESI(t, map[string]int{"Market Scammer": 2117477599, "Market Trickster": 2118503862}, nil)
+
+	names := []string{"Market Scammer", "Market Trickster"}
+	expectedIDs := []int{2117477599, 2118503862}
+
+	ids, err := ResolveNamesToCharacterIDs(context.Background(), names)
+	if err != nil {
+		t.Errorf("Error occurred: %v", err)
+		return
+	}
+
+	if !reflect.DeepEqual(ids, expectedIDs) {
+		t.Errorf("Expected IDs: %v, got: %v", expectedIDs, ids)
+	}
+}
+
+func TestGetItemsFromKillmailCaching(t *testing.T) {
+	previous := sharedCache
+	SetCache(NewMemoryCache())
+	defer SetCache(previous)
+
+	var calls int32
+	newFakeESI(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if r.URL.Path != "/killmails/930000001/abc/" {
+			t.Errorf("Unexpected path: %v", r.URL.Path)
+		}
+		fmt.Fprint(w, testKillmailJSON)
+	})
+
+	expectedItems := []int{3828, 5973, 3467}
+	for i := 0; i < 2; i++ {
+		items, killmailTime, err := GetItemsFromKillmail(context.Background(), 930000001, "abc")
+		if err != nil {
+			t.Errorf("Error occurred: %v", err)
+			return
+		}
+
+		if !reflect.DeepEqual(items, expectedItems) || killmailTime.IsZero() {
+			t.Errorf("Expected items: %v, got: %v at %v", expectedItems, items, killmailTime)
+		}
+	}
+
+	if calls != 1 {
+		t.Errorf("Expected the second lookup to be served from cache, got %d requests", calls)
+	}
 }
 
 func TestResolveItemNamesToIDs(t *testing.T) {
+	previous := sharedCache
+	SetCache(NewMemoryCache())
+	defer SetCache(previous)
+
+	newFakeIDsESI(t, nil, map[string]int{"Loki": 29990, "Kestrel": 602})
+
 	names := []string{"Loki", "Kestrel"}
 	expectedIDs := []int{29990, 602}
 
-	ids, err := ResolveItemNamesToIDs(names)
+	ids, err := ResolveItemNamesToIDs(context.Background(), names)
 	if err != nil {
 		t.Errorf("Error occurred: %v", err)
 		return
@@ -88,6 +332,4 @@ func TestResolveItemNamesToIDs(t *testing.T) {
 	if !reflect.DeepEqual(ids, expectedIDs) {
 		t.Errorf("Expected IDs: %v, got: %v", expectedIDs, ids)
 	}
-
-	t.Logf("IDs: %v", ids)
 }
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// FitFormat is a text format fits can be exported to.
+type FitFormat string
+
+const (
+	// FitFormatEFT is the EFT text format, understood by Pyfa and the in-game fitting window.
+	FitFormatEFT FitFormat = "EFT"
+	// FitFormatDNA is the ship DNA format used by in-game fitting links.
+	FitFormatDNA FitFormat = "DNA"
+	// FitFormatXML is the CCP XML fittings format the client imports.
+	FitFormatXML FitFormat = "XML"
+)
+
+// FitFormats lists every format fits can be exported to.
+var FitFormats = []FitFormat{FitFormatEFT, FitFormatDNA, FitFormatXML}
+
+// Extension returns the file extension of the format.
+func (f FitFormat) Extension() string {
+	switch f {
+	case FitFormatXML:
+		return ".xml"
+	default:
+		return ".txt"
+	}
+}
+
+// NamedFit is a fit ready for export, with the names of its types.
+type NamedFit struct {
+	Name  string
+	Fit   Fit
+	Names map[int]ResolvedName
+}
+
+// dnaSlotOrder is the order ship DNA lists the fitted slots in.
+var dnaSlotOrder = []Slot{SlotSubsystem, SlotHigh, SlotMid, SlotLow, SlotRig}
+
+// DNA renders the fit as a ship DNA string: the ship type ID followed by every module, drone
+// and loaded charge as typeID;quantity, separated by colons and ending with "::".
+func (f Fit) DNA() string {
+	parts := []string{strconv.Itoa(f.ShipTypeID)}
+	add := func(stacks []FitItem) {
+		for _, stack := range stacks {
+			parts = append(parts, fmt.Sprintf("%d;%d", stack.TypeID, stack.Quantity))
+		}
+	}
+
+	for _, slot := range dnaSlotOrder {
+		modules := make([]FitItem, 0)
+		for _, item := range f.InSlot(slot) {
+			if !item.Charge {
+				item.Quantity = 1
+				modules = append(modules, item)
+			}
+		}
+		add(stackItems(modules))
+	}
+
+	add(f.stacks(SlotDroneBay, SlotFighterBay))
+
+	charges := make([]FitItem, 0)
+	for _, item := range f.Items {
+		if item.Charge {
+			charges = append(charges, item)
+		}
+	}
+	add(stackItems(charges))
+
+	return strings.Join(parts, ":") + "::"
+}
+
+// xmlFittings is the root of the CCP XML fittings format.
+type xmlFittings struct {
+	XMLName  xml.Name     `xml:"fittings"`
+	Fittings []xmlFitting `xml:"fitting"`
+}
+
+type xmlFitting struct {
+	Name        string        `xml:"name,attr"`
+	Description xmlValue      `xml:"description"`
+	ShipType    xmlValue      `xml:"shipType"`
+	Hardware    []xmlHardware `xml:"hardware"`
+}
+
+type xmlValue struct {
+	Value string `xml:"value,attr"`
+}
+
+type xmlHardware struct {
+	Quantity int64  `xml:"qty,attr,omitempty"`
+	Slot     string `xml:"slot,attr"`
+	Type     string `xml:"type,attr"`
+}
+
+// xmlSlots maps the fitted slots to their XML slot name and first flag.
+var xmlSlots = []struct {
+	slot      Slot
+	name      string
+	firstFlag int
+}{
+	{SlotLow, "low slot", flagLoSlot0},
+	{SlotMid, "med slot", flagMedSlot0},
+	{SlotHigh, "hi slot", flagHiSlot0},
+	{SlotRig, "rig slot", flagRigSlot0},
+	{SlotSubsystem, "subsystem slot", flagSubSystemSlot0},
+}
+
+// xmlFitting converts the fit to its CCP XML form.
+func (n NamedFit) xmlFitting() xmlFitting {
+	fitting := xmlFitting{
+		Name:     n.Name,
+		ShipType: xmlValue{Value: typeName(n.Names, n.Fit.ShipTypeID)},
+	}
+
+	for _, slot := range xmlSlots {
+		for _, item := range n.Fit.InSlot(slot.slot) {
+			if item.Charge {
+				continue
+			}
+			fitting.Hardware = append(fitting.Hardware, xmlHardware{
+				Slot: fmt.Sprintf("%s %d", slot.name, item.Flag-slot.firstFlag),
+				Type: typeName(n.Names, item.TypeID),
+			})
+		}
+	}
+
+	for _, stack := range n.Fit.stacks(SlotDroneBay) {
+		fitting.Hardware = append(fitting.Hardware, xmlHardware{Quantity: stack.Quantity, Slot: "drone bay", Type: typeName(n.Names, stack.TypeID)})
+	}
+	for _, stack := range n.Fit.stacks(SlotFighterBay) {
+		fitting.Hardware = append(fitting.Hardware, xmlHardware{Quantity: stack.Quantity, Slot: "fighter bay", Type: typeName(n.Names, stack.TypeID)})
+	}
+	for _, stack := range n.Fit.stacks(SlotCargo) {
+		fitting.Hardware = append(fitting.Hardware, xmlHardware{Quantity: stack.Quantity, Slot: "cargo", Type: typeName(n.Names, stack.TypeID)})
+	}
+
+	return fitting
+}
+
+// ExportFits writes the fits to w in the given format. EFT fits are separated by a blank line,
+// DNA strings are written one per line, and XML fits share a single fittings document.
+func ExportFits(w io.Writer, format FitFormat, fits []NamedFit) error {
+	switch format {
+	case FitFormatEFT:
+		texts := make([]string, 0, len(fits))
+		for _, fit := range fits {
+			texts = append(texts, fit.Fit.EFT(fit.Names, fit.Name))
+		}
+		_, err := io.WriteString(w, strings.Join(texts, "\n"))
+		return err
+	case FitFormatDNA:
+		for _, fit := range fits {
+			if _, err := fmt.Fprintln(w, fit.Fit.DNA()); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FitFormatXML:
+		document := xmlFittings{Fittings: make([]xmlFitting, 0, len(fits))}
+		for _, fit := range fits {
+			document.Fittings = append(document.Fittings, fit.xmlFitting())
+		}
+
+		if _, err := io.WriteString(w, xml.Header); err != nil {
+			return err
+		}
+		encoder := xml.NewEncoder(w)
+		encoder.Indent("", "  ")
+		if err := encoder.Encode(document); err != nil {
+			return err
+		}
+		_, err := io.WriteString(w, "\n")
+		return err
+	default:
+		return fmt.Errorf("unknown fit format %q", format)
+	}
+}
+
+// ExportFit renders a single fit in the given format.
+func ExportFit(format FitFormat, fit NamedFit) (string, error) {
+	var text strings.Builder
+	if err := ExportFits(&text, format, []NamedFit{fit}); err != nil {
+		return "", err
+	}
+	return text.String(), nil
+}
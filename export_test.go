@@ -0,0 +1,133 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func testNamedFit() NamedFit {
+	killmail := Killmail{KillmailID: 42, Victim: Victim{ShipTypeID: 587, Items: []KillmailItem{
+		{ItemTypeID: 2873, Flag: 28, QuantityDestroyed: 1},
+		{ItemTypeID: 2873, Flag: 27, QuantityDropped: 1},
+		{ItemTypeID: 12608, Flag: 27, QuantityDestroyed: 80},
+		{ItemTypeID: 12608, Flag: 28, QuantityDropped: 80},
+		{ItemTypeID: 447, Flag: 20, QuantityDestroyed: 1},
+		{ItemTypeID: 2048, Flag: 11, QuantityDropped: 1},
+		{ItemTypeID: 2456, Flag: 87, QuantityDestroyed: 3},
+		{ItemTypeID: 12608, Flag: 5, QuantityDropped: 500},
+	}}}
+
+	names := map[int]ResolvedName{
+		587:   {ID: 587, Name: "Rifter", Category: CategoryInventoryType},
+		2873:  {ID: 2873, Name: "125mm Gatling AutoCannon II", Category: CategoryInventoryType},
+		12608: {ID: 12608, Name: "Hail S", Category: CategoryInventoryType},
+		447:   {ID: 447, Name: "Warp Scrambler I", Category: CategoryInventoryType},
+		2048:  {ID: 2048, Name: "Damage Control II", Category: CategoryInventoryType},
+		2456:  {ID: 2456, Name: "Hobgoblin II & Co", Category: CategoryInventoryType},
+	}
+
+	return summarizeLoss(killmail, names).NamedFit()
+}
+
+func TestFitDNA(t *testing.T) {
+	dna := testNamedFit().Fit.DNA()
+	expected := "587:2873;2:447;1:2048;1:2456;3:12608;160::"
+	if dna != expected {
+		t.Errorf("Expected DNA %v, got: %v", expected, dna)
+	}
+}
+
+func TestExportFitsXML(t *testing.T) {
+	fit := testNamedFit()
+
+	var out strings.Builder
+	if err := ExportFits(&out, FitFormatXML, []NamedFit{fit, fit}); err != nil {
+		t.Errorf("Error occurred: %v", err)
+		return
+	}
+
+	xml := out.String()
+	for _, expected := range []string{
+		`<?xml version="1.0" encoding="UTF-8"?>`,
+		`<fitting name="Killmail 42">`,
+		`<shipType value="Rifter"></shipType>`,
+		`<hardware slot="low slot 0" type="Damage Control II"></hardware>`,
+		`<hardware slot="med slot 1" type="Warp Scrambler I"></hardware>`,
+		`<hardware slot="hi slot 1" type="125mm Gatling AutoCannon II"></hardware>`,
+		`<hardware qty="3" slot="drone bay" type="Hobgoblin II &amp; Co"></hardware>`,
+		`<hardware qty="500" slot="cargo" type="Hail S"></hardware>`,
+	} {
+		if !strings.Contains(xml, expected) {
+			t.Errorf("Expected %v in export:\n%v", expected, xml)
+		}
+	}
+	if strings.Count(xml, "<fitting ") != 2 {
+		t.Errorf("Expected both fits in a single document:\n%v", xml)
+	}
+}
+
+func TestExportFits(t *testing.T) {
+	fit := testNamedFit()
+
+	var out strings.Builder
+	if err := ExportFits(&out, FitFormatDNA, []NamedFit{fit, fit}); err != nil {
+		t.Errorf("Error occurred: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != 2 {
+		t.Errorf("Expected one DNA line per fit, got: %v", lines)
+	}
+
+	out.Reset()
+	if err := ExportFits(&out, FitFormatEFT, []NamedFit{fit, fit}); err != nil {
+		t.Errorf("Error occurred: %v", err)
+	}
+	if strings.Count(out.String(), "[Rifter, Killmail 42]") != 2 || !strings.Contains(out.String(), "Hail S x500\n\n[Rifter") {
+		t.Errorf("Unexpected EFT export:\n%v", out.String())
+	}
+
+	if _, err := ExportFit(FitFormat("PNG"), fit); err == nil {
+		t.Errorf("Expected an unknown format to be rejected")
+	}
+}
+
+func TestExportScriptedModule(t *testing.T) {
+	previous := staticData
+	SetStaticData(&StaticData{
+		Types: map[int]sdeType{
+			3244:  {Name: "Warp Disruptor II", GroupID: GroupWarpScrambler},
+			45010: {Name: "Focused Warp Disruption Script", GroupID: 1702},
+		},
+		Groups: map[int]sdeGroup{
+			GroupWarpScrambler: {Name: "Warp Scrambler", CategoryID: SDECategoryModule},
+			1702:               {Name: "Warp Disruption Script", CategoryID: SDECategoryCharge},
+		},
+	})
+	defer SetStaticData(previous)
+
+	killmail := Killmail{KillmailID: 7, Victim: Victim{ShipTypeID: 587, Items: []KillmailItem{
+		{ItemTypeID: 45010, Flag: 19, QuantityDestroyed: 1},
+		{ItemTypeID: 3244, Flag: 19, QuantityDestroyed: 1},
+	}}}
+	fit := NamedFit{
+		Name: "Killmail 7",
+		Fit:  FitFromKillmail(killmail),
+		Names: map[int]ResolvedName{
+			587:   {ID: 587, Name: "Rifter", Category: CategoryInventoryType},
+			3244:  {ID: 3244, Name: "Warp Disruptor II", Category: CategoryInventoryType},
+			45010: {ID: 45010, Name: "Focused Warp Disruption Script", Category: CategoryInventoryType},
+		},
+	}
+
+	if dna := fit.Fit.DNA(); dna != "587:3244;1:45010;1::" {
+		t.Errorf("Unexpected DNA: %v", dna)
+	}
+
+	xml, err := ExportFit(FitFormatXML, fit)
+	if err != nil {
+		t.Errorf("Error occurred: %v", err)
+		return
+	}
+	if !strings.Contains(xml, `<hardware slot="med slot 0" type="Warp Disruptor II"></hardware>`) || strings.Contains(xml, `type="Focused Warp Disruption Script"`) {
+		t.Errorf("Expected only the disruptor fitted in the med slot:\n%v", xml)
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+// Slot is the part of a ship an item was located in, derived from the killmail item flag.
+type Slot int
+
+const (
+	SlotOther Slot = iota
+	SlotHigh
+	SlotMid
+	SlotLow
+	SlotRig
+	SlotSubsystem
+	SlotDroneBay
+	SlotCargo
+	SlotFighterBay
+)
+
+// Inventory flag ranges used by ESI killmails.
+const (
+	flagCargo          = 5
+	flagLoSlot0        = 11
+	flagLoSlot7        = 18
+	flagMedSlot0       = 19
+	flagMedSlot7       = 26
+	flagHiSlot0        = 27
+	flagHiSlot7        = 34
+	flagDroneBay       = 87
+	flagRigSlot0       = 92
+	flagRigSlot7       = 99
+	flagSubSystemSlot0 = 125
+	flagSubSystemSlot7 = 132
+	flagFighterBay     = 158
+	flagFighterTube0   = 159
+	flagFighterTube4   = 163
+)
+
+var slotNames = map[Slot]string{
+	SlotOther:      "Other",
+	SlotHigh:       "High",
+	SlotMid:        "Mid",
+	SlotLow:        "Low",
+	SlotRig:        "Rig",
+	SlotSubsystem:  "Subsystem",
+	SlotDroneBay:   "Drone Bay",
+	SlotCargo:      "Cargo",
+	SlotFighterBay: "Fighter Bay",
+}
+
+func (s Slot) String() string {
+	return slotNames[s]
+}
+
+// Fitted reports whether items in the slot are fitted to the ship rather than carried.
+func (s Slot) Fitted() bool {
+	return s == SlotHigh || s == SlotMid || s == SlotLow || s == SlotRig || s == SlotSubsystem
+}
+
+// SlotForFlag classifies a killmail item flag.
+func SlotForFlag(flag int) Slot {
+	switch {
+	case flag >= flagHiSlot0 && flag <= flagHiSlot7:
+		return SlotHigh
+	case flag >= flagMedSlot0 && flag <= flagMedSlot7:
+		return SlotMid
+	case flag >= flagLoSlot0 && flag <= flagLoSlot7:
+		return SlotLow
+	case flag >= flagRigSlot0 && flag <= flagRigSlot7:
+		return SlotRig
+	case flag >= flagSubSystemSlot0 && flag <= flagSubSystemSlot7:
+		return SlotSubsystem
+	case flag == flagDroneBay:
+		return SlotDroneBay
+	case flag == flagCargo:
+		return SlotCargo
+	case flag == flagFighterBay || (flag >= flagFighterTube0 && flag <= flagFighterTube4):
+		return SlotFighterBay
+	default:
+		return SlotOther
+	}
+}
+
+// FitItem is a single item of a fit.
+type FitItem struct {
+	TypeID   int
+	Flag     int
+	Slot     Slot
+	Quantity int64
+	// Charge is set for items loaded into a fitted module, such as ammunition or scripts.
+	Charge bool
+}
+
+// Fit is the fitting of a lost ship as reconstructed from its killmail.
+type Fit struct {
+	ShipTypeID int
+	Items      []FitItem
+}
+
+// FitFromKillmail reconstructs the fit of the victim's ship.
+// A fitted slot may hold both a module and its loaded charge, sharing the flag. See loadedCharge
+// for how the two are told apart.
+func FitFromKillmail(killmail Killmail) Fit {
+	fit := Fit{ShipTypeID: killmail.Victim.ShipTypeID}
+
+	moduleAt := make(map[int]int)
+	for _, item := range killmail.Victim.Items {
+		fitItem := FitItem{
+			TypeID:   item.ItemTypeID,
+			Flag:     item.Flag,
+			Slot:     SlotForFlag(item.Flag),
+			Quantity: item.Quantity(),
+		}
+
+		if fitItem.Slot.Fitted() {
+			if i, ok := moduleAt[item.Flag]; ok {
+				if loadedCharge(fit.Items[i], fitItem) {
+					fitItem.Charge = true
+				} else {
+					fit.Items[i].Charge = true
+					moduleAt[item.Flag] = len(fit.Items)
+				}
+			} else {
+				moduleAt[item.Flag] = len(fit.Items)
+			}
+		}
+
+		fit.Items = append(fit.Items, fitItem)
+	}
+
+	return fit
+}
+
+// loadedCharge reports whether candidate, rather than module, is the charge of a slot holding both.
+// The SDE category decides when the types are known. Otherwise the larger stack is the charge,
+// and on a tie, as with a script or crystal, the item listed first stays the module.
+func loadedCharge(module FitItem, candidate FitItem) bool {
+	candidateCharge, candidateKnown := isChargeType(candidate.TypeID)
+	moduleCharge, moduleKnown := isChargeType(module.TypeID)
+
+	switch {
+	case candidateKnown && moduleKnown && candidateCharge != moduleCharge:
+		return candidateCharge
+	case candidateKnown && !moduleKnown:
+		return candidateCharge
+	case moduleKnown && !candidateKnown:
+		return !moduleCharge
+	}
+	return candidate.Quantity >= module.Quantity
+}
+
+// isChargeType reports whether a type is a charge, and whether the SDE knows the type at all.
+func isChargeType(typeID int) (charge bool, known bool) {
+	info, ok := LookupType(typeID)
+	if !ok {
+		return false, false
+	}
+	return info.CategoryID == SDECategoryCharge, true
+}
+
+// Modules returns the modules fitted to the ship, leaving out charges, drones and cargo.
+func (f Fit) Modules() []FitItem {
+	modules := make([]FitItem, 0)
+	for _, item := range f.Items {
+		if item.Slot.Fitted() && !item.Charge {
+			modules = append(modules, item)
+		}
+	}
+	return modules
+}
+
+// InSlot returns the items located in the given slot, including charges.
+func (f Fit) InSlot(slot Slot) []FitItem {
+	items := make([]FitItem, 0)
+	for _, item := range f.Items {
+		if item.Slot == slot {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// ModuleTypeIDs returns the type ID of every fitted module, once per module.
+func (f Fit) ModuleTypeIDs() []int {
+	ids := make([]int, 0)
+	for _, module := range f.Modules() {
+		ids = append(ids, module.TypeID)
+	}
+	return ids
+}
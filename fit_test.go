@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestSlotForFlag(t *testing.T) {
+	tests := map[int]Slot{
+		5:   SlotCargo,
+		11:  SlotLow,
+		18:  SlotLow,
+		19:  SlotMid,
+		27:  SlotHigh,
+		34:  SlotHigh,
+		87:  SlotDroneBay,
+		92:  SlotRig,
+		125: SlotSubsystem,
+		158: SlotFighterBay,
+		161: SlotFighterBay,
+		89:  SlotOther,
+	}
+
+	for flag, expected := range tests {
+		if slot := SlotForFlag(flag); slot != expected {
+			t.Errorf("Expected %v for flag %d, got: %v", expected, flag, slot)
+		}
+	}
+}
+
+func TestFitFromKillmail(t *testing.T) {
+	var killmail Killmail
+	err := json.Unmarshal([]byte(`{
+		"killmail_id": 1,
+		"killmail_time": "2023-06-01T12:34:56Z",
+		"victim": {
+			"ship_type_id": 587,
+			"items": [
+				{"flag": 5, "item_type_id": 5443, "quantity_dropped": 1},
+				{"flag": 19, "item_type_id": 5443, "quantity_destroyed": 1},
+				{"flag": 27, "item_type_id": 21638, "quantity_destroyed": 40},
+				{"flag": 27, "item_type_id": 2929, "quantity_destroyed": 1},
+				{"flag": 27, "item_type_id": 21638, "quantity_dropped": 20},
+				{"flag": 87, "item_type_id": 2488, "quantity_dropped": 2},
+				{"flag": 92, "item_type_id": 31788, "quantity_destroyed": 1}
+			]
+		}
+	}`), &killmail)
+	if err != nil {
+		t.Errorf("Error occurred: %v", err)
+		return
+	}
+
+	fit := FitFromKillmail(killmail)
+
+	expectedModules := []int{5443, 2929, 31788}
+	if !reflect.DeepEqual(fit.ModuleTypeIDs(), expectedModules) {
+		t.Errorf("Expected modules: %v, got: %v", expectedModules, fit.ModuleTypeIDs())
+	}
+
+	if len(fit.InSlot(SlotHigh)) != 3 || len(fit.InSlot(SlotDroneBay)) != 1 || len(fit.InSlot(SlotCargo)) != 1 {
+		t.Errorf("Unexpected slot layout: %+v", fit.Items)
+	}
+}
+
+func TestFitFromKillmailScriptedModule(t *testing.T) {
+	previous := staticData
+	SetStaticData(&StaticData{
+		Types: map[int]sdeType{
+			3244:  {Name: "Warp Disruptor II", GroupID: GroupWarpScrambler},
+			45010: {Name: "Focused Warp Disruption Script", GroupID: 1702},
+		},
+		Groups: map[int]sdeGroup{
+			GroupWarpScrambler: {Name: "Warp Scrambler", CategoryID: SDECategoryModule},
+			1702:               {Name: "Warp Disruption Script", CategoryID: SDECategoryCharge},
+		},
+	})
+	defer SetStaticData(previous)
+
+	module := KillmailItem{ItemTypeID: 3244, Flag: 19, QuantityDestroyed: 1}
+	script := KillmailItem{ItemTypeID: 45010, Flag: 19, QuantityDestroyed: 1}
+
+	for _, items := range [][]KillmailItem{{module, script}, {script, module}} {
+		fit := FitFromKillmail(Killmail{Victim: Victim{ShipTypeID: 587, Items: items}})
+		if ids := fit.ModuleTypeIDs(); !reflect.DeepEqual(ids, []int{3244}) {
+			t.Errorf("Expected the disruptor as the module for items %+v, got: %v", items, ids)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// flightGroup deduplicates concurrent calls sharing a key: the first caller starts the
+// call and everyone asking for the same key while it runs waits for its result.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	done    chan struct{}
+	value   interface{}
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// requests deduplicates the ESI and zKillboard requests made by the package level functions.
+var requests = &flightGroup{}
+
+// do runs fn once for all concurrent callers of key. The call keeps running as long as at
+// least one caller still waits for it, so a caller giving up does not fail the others.
+func (g *flightGroup) do(ctx context.Context, key string, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	call, ok := g.calls[key]
+	if !ok {
+		callCtx, cancel := context.WithCancel(context.Background())
+		call = &flightCall{done: make(chan struct{}), cancel: cancel}
+		g.calls[key] = call
+
+		go func() {
+			call.value, call.err = fn(callCtx)
+			cancel()
+
+			g.mu.Lock()
+			g.forget(key, call)
+			g.mu.Unlock()
+			close(call.done)
+		}()
+	}
+	call.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.value, call.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		call.waiters--
+		if call.waiters == 0 {
+			// Forget the abandoned call right away, so a caller arriving before it
+			// returns starts a fresh one instead of joining a cancelled one.
+			call.cancel()
+			g.forget(key, call)
+		}
+		g.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// forget removes call from the group unless a newer call already took its key.
+// The caller must hold g.mu.
+func (g *flightGroup) forget(key string, call *flightCall) {
+	if g.calls[key] == call {
+		delete(g.calls, key)
+	}
+}
+
+// doShared is the typed form of flightGroup.do.
+func doShared[T any](ctx context.Context, g *flightGroup, key string, fn func(ctx context.Context) (T, error)) (T, error) {
+	value, err := g.do(ctx, key, func(ctx context.Context) (interface{}, error) {
+		return fn(ctx)
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return value.(T), nil
+}
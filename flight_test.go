@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFlightGroupSharesResult(t *testing.T) {
+	group := &flightGroup{}
+	var calls int32
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := doShared(context.Background(), group, "killmail:1", func(ctx context.Context) (int, error) {
+				atomic.AddInt32(&calls, 1)
+				time.Sleep(20 * time.Millisecond)
+				return 42, nil
+			})
+			if err != nil || value != 42 {
+				t.Errorf("Expected 42, got: %v, %v", value, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("Expected a single call, got: %d", calls)
+	}
+}
+
+func TestFlightGroupSurvivesCancelledWaiter(t *testing.T) {
+	group := &flightGroup{}
+	release := make(chan struct{})
+
+	fn := func(ctx context.Context) (int, error) {
+		select {
+		case <-release:
+			return 7, nil
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	firstDone := make(chan error)
+	go func() {
+		_, err := doShared(cancelled, group, "losses:1_2", fn)
+		firstDone <- err
+	}()
+
+	secondDone := make(chan int)
+	go func() {
+		value, _ := doShared(context.Background(), group, "losses:1_2", fn)
+		secondDone <- value
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	if err := <-firstDone; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected %v, got: %v", context.Canceled, err)
+	}
+
+	close(release)
+	if value := <-secondDone; value != 7 {
+		t.Errorf("Expected the remaining waiter to get 7, got: %v", value)
+	}
+}
+
+func TestFlightGroupRestartsAbandonedCall(t *testing.T) {
+	group := &flightGroup{}
+
+	// The abandoned call takes a while to notice its cancellation.
+	slow := func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		time.Sleep(50 * time.Millisecond)
+		return 0, ctx.Err()
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	firstDone := make(chan error)
+	go func() {
+		_, err := doShared(cancelled, group, "stats:1", slow)
+		firstDone <- err
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	if err := <-firstDone; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected %v, got: %v", context.Canceled, err)
+	}
+
+	value, err := doShared(context.Background(), group, "stats:1", func(ctx context.Context) (int, error) {
+		return 9, nil
+	})
+	if err != nil || value != 9 {
+		t.Errorf("Expected a fresh call returning 9, got: %v, %v", value, err)
+	}
+}
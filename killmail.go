@@ -0,0 +1,93 @@
+package main
+
+import "time"
+
+// Killmail is a killmail as published by ESI.
+type Killmail struct {
+	KillmailID    int        `json:"killmail_id"`
+	KillmailTime  time.Time  `json:"killmail_time"`
+	SolarSystemID int        `json:"solar_system_id"`
+	MoonID        int        `json:"moon_id,omitempty"`
+	WarID         int        `json:"war_id,omitempty"`
+	Victim        Victim     `json:"victim"`
+	Attackers     []Attacker `json:"attackers"`
+}
+
+// Victim is the losing side of a killmail.
+type Victim struct {
+	CharacterID   int            `json:"character_id,omitempty"`
+	CorporationID int            `json:"corporation_id,omitempty"`
+	AllianceID    int            `json:"alliance_id,omitempty"`
+	FactionID     int            `json:"faction_id,omitempty"`
+	ShipTypeID    int            `json:"ship_type_id"`
+	DamageTaken   int            `json:"damage_taken"`
+	Items         []KillmailItem `json:"items,omitempty"`
+	Position      *Position      `json:"position,omitempty"`
+}
+
+// Attacker is a single participant on the winning side of a killmail.
+// NPCs carry no character, and structures carry no ship.
+type Attacker struct {
+	CharacterID    int     `json:"character_id,omitempty"`
+	CorporationID  int     `json:"corporation_id,omitempty"`
+	AllianceID     int     `json:"alliance_id,omitempty"`
+	FactionID      int     `json:"faction_id,omitempty"`
+	ShipTypeID     int     `json:"ship_type_id,omitempty"`
+	WeaponTypeID   int     `json:"weapon_type_id,omitempty"`
+	DamageDone     int     `json:"damage_done"`
+	FinalBlow      bool    `json:"final_blow"`
+	SecurityStatus float64 `json:"security_status"`
+}
+
+// KillmailItem is an item the victim had fitted or carried. Containers hold their contents in Items.
+type KillmailItem struct {
+	ItemTypeID        int            `json:"item_type_id"`
+	Flag              int            `json:"flag"`
+	Singleton         int            `json:"singleton"`
+	QuantityDestroyed int64          `json:"quantity_destroyed,omitempty"`
+	QuantityDropped   int64          `json:"quantity_dropped,omitempty"`
+	Items             []KillmailItem `json:"items,omitempty"`
+}
+
+// Position is the location of the victim in space.
+type Position struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	Z float64 `json:"z"`
+}
+
+// Quantity returns the total number of items, destroyed or dropped.
+func (i KillmailItem) Quantity() int64 {
+	return i.QuantityDestroyed + i.QuantityDropped
+}
+
+// Dropped reports whether any of the items survived the loss.
+func (i KillmailItem) Dropped() bool {
+	return i.QuantityDropped > 0
+}
+
+// FinalBlow returns the attacker who landed the final blow, if any.
+func (k Killmail) FinalBlow() (Attacker, bool) {
+	for _, attacker := range k.Attackers {
+		if attacker.FinalBlow {
+			return attacker, true
+		}
+	}
+	return Attacker{}, false
+}
+
+// TypeIDs returns the type IDs of the victim's ship and every item on the killmail, including container contents.
+func (k Killmail) TypeIDs() []int {
+	ids := []int{k.Victim.ShipTypeID}
+
+	var walk func(items []KillmailItem)
+	walk = func(items []KillmailItem) {
+		for _, item := range items {
+			ids = append(ids, item.ItemTypeID)
+			walk(item.Items)
+		}
+	}
+	walk(k.Victim.Items)
+
+	return unique(ids)
+}
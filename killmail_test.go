@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const testKillmailJSON = `{
+	"attackers": [
+		{"character_id": 95465499, "corporation_id": 98000001, "damage_done": 120, "final_blow": false, "security_status": -2.3, "ship_type_id": 11198, "weapon_type_id": 2185},
+		{"corporation_id": 1000125, "damage_done": 40, "final_blow": true, "security_status": 0, "ship_type_id": 34495}
+	],
+	"killmail_id": 930000001,
+	"killmail_time": "2023-06-01T12:34:56Z",
+	"solar_system_id": 30002187,
+	"victim": {
+		"alliance_id": 99000001,
+		"character_id": 2117477599,
+		"corporation_id": 98000002,
+		"damage_taken": 160,
+		"items": [
+			{"flag": 27, "item_type_id": 3828, "quantity_destroyed": 1, "singleton": 0},
+			{"flag": 19, "item_type_id": 5973, "quantity_dropped": 1, "singleton": 0},
+			{"flag": 5, "item_type_id": 3467, "quantity_destroyed": 1, "singleton": 0, "items": [
+				{"flag": 0, "item_type_id": 34, "quantity_dropped": 500, "singleton": 0}
+			]}
+		],
+		"position": {"x": 1.5, "y": -2, "z": 3},
+		"ship_type_id": 587
+	}
+}`
+
+func TestGetKillmail(t *testing.T) {
+	calls := 0
+	client := newFakeESI(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path != "/killmails/930000001/abc/" {
+			t.Errorf("Unexpected path: %v", r.URL.Path)
+		}
+		fmt.Fprint(w, testKillmailJSON)
+	})
+
+	killmail, err := GetKillmail(context.Background(), 930000001, "abc")
+	if err != nil {
+		t.Errorf("Error occurred: %v", err)
+		return
+	}
+
+	if !killmail.KillmailTime.Equal(time.Date(2023, 6, 1, 12, 34, 56, 0, time.UTC)) {
+		t.Errorf("Unexpected killmail time: %v", killmail.KillmailTime)
+	}
+	if killmail.Victim.ShipTypeID != 587 || killmail.Victim.AllianceID != 99000001 || killmail.SolarSystemID != 30002187 {
+		t.Errorf("Unexpected victim: %+v", killmail.Victim)
+	}
+	if killmail.Victim.Items[2].Items[0].Quantity() != 500 || !killmail.Victim.Items[2].Items[0].Dropped() {
+		t.Errorf("Unexpected container contents: %+v", killmail.Victim.Items[2])
+	}
+	if finalBlow, ok := killmail.FinalBlow(); !ok || finalBlow.ShipTypeID != 34495 {
+		t.Errorf("Unexpected final blow: %+v", finalBlow)
+	}
+
+	expectedIDs := []int{587, 3828, 5973, 3467, 34}
+	if !reflect.DeepEqual(killmail.TypeIDs(), expectedIDs) {
+		t.Errorf("Expected type IDs: %v, got: %v", expectedIDs, killmail.TypeIDs())
+	}
+
+	items, _, err := GetItemsFromKillmail(context.Background(), 930000001, "abc")
+	if err != nil {
+		t.Errorf("Error occurred: %v", err)
+		return
+	}
+	if !reflect.DeepEqual(items, []int{3828, 5973, 3467}) || calls != 1 {
+		t.Errorf("Unexpected items %v after %d calls", items, calls)
+	}
+
+	// The killmail is cached once by ID, not a second time as a raw response.
+	req, _ := client.newRequest(context.Background(), "GET", "/killmails/930000001/abc/", nil, nil)
+	if _, ok := client.Cache.Get("esi:" + req.URL.String()); ok {
+		t.Errorf("Expected the killmail response to stay out of the response cache")
+	}
+}
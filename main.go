@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"image/color"
 	"strconv"
-	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"fyne.io/fyne/v2"
@@ -12,6 +15,7 @@ import (
 	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/data/binding"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/widget"
 )
@@ -19,39 +23,78 @@ import (
 var playerTopShips = binding.BindStringList(
 	&[]string{},
 )
-var currentUser int
 
-var isWorking = false
+// currentUser is the character ID of the pilot whose ships are listed, accessed atomically.
+var currentUser int64
 
-func InputWidgetWatcher(entry *widget.Entry, button *widget.Button) {
-	oldIsWorking := isWorking
-	for {
-		if oldIsWorking != isWorking {
-			if isWorking {
-				entry.Disable()
-				button.Disable()
-			} else {
-				entry.Enable()
-				button.Enable()
-			}
-			oldIsWorking = isWorking
-		}
-		time.Sleep(10 * time.Millisecond)
+// playerProfile holds the profile text of the analyzed pilot, shown above the ship list.
+var playerProfile = binding.NewString()
+
+// playerCorpHistory holds the corporation history and its warnings, shown under the profile.
+var playerCorpHistory = binding.NewString()
+
+// runningAnalyses counts the analyses in progress.
+var runningAnalyses int32
+
+var analysisMu sync.Mutex
+var cancelAnalysis context.CancelFunc
+
+// isWorking reports whether an analysis is in progress.
+func isWorking() bool {
+	return atomic.LoadInt32(&runningAnalyses) > 0
+}
+
+// startAnalysis cancels the analysis in progress, if any, and returns the context for a new one.
+// Every call must be paired with a call to finishAnalysis.
+func startAnalysis() context.Context {
+	analysisMu.Lock()
+	defer analysisMu.Unlock()
+
+	if cancelAnalysis != nil {
+		cancelAnalysis()
 	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelAnalysis = cancel
+
+	atomic.AddInt32(&runningAnalyses, 1)
+	return ctx
+}
+
+// finishAnalysis marks an analysis returned by startAnalysis as done.
+func finishAnalysis() {
+	atomic.AddInt32(&runningAnalyses, -1)
 }
 
-var objectsToHide []*canvas.Text
+// CancelAnalysis aborts the analysis in progress along with its in-flight requests.
+func CancelAnalysis() {
+	analysisMu.Lock()
+	defer analysisMu.Unlock()
 
-func HideObjects() {
-	for _, o := range objectsToHide {
-		o.Hide()
+	if cancelAnalysis != nil {
+		cancelAnalysis()
+		cancelAnalysis = nil
 	}
 }
 
-func UpdateDetailInfo(newData [][]string, w fyne.Window, subContainer *fyne.Container, list *widget.List) {
-	// HideObjects()
-	objectsToHide = []*canvas.Text{}
+// InputWidgetWatcher enables the cancel button only while an analysis is in progress.
+func InputWidgetWatcher(cancelButton *widget.Button) {
+	oldIsWorking := !isWorking()
+	for {
+		if working := isWorking(); oldIsWorking != working {
+			if working {
+				cancelButton.Enable()
+			} else {
+				cancelButton.Disable()
+			}
+			oldIsWorking = working
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
 
+// UpdateDetailInfo replaces the detail table with newData, whose first row is the header.
+// onSelected, when set, is called with the index of the data row the user clicks, not counting the header.
+func UpdateDetailInfo(newData [][]string, w fyne.Window, subContainer *fyne.Container, list *widget.List, onSelected func(row int)) {
 	newDetailInfo := widget.NewTable(
 		func() (int, int) {
 			if len(newData) == 0 {
@@ -61,9 +104,7 @@ func UpdateDetailInfo(newData [][]string, w fyne.Window, subContainer *fyne.Cont
 			}
 		},
 		func() fyne.CanvasObject {
-			o := canvas.NewText("template", color.White)
-			objectsToHide = append(objectsToHide, o)
-			return o
+			return canvas.NewText("template", color.White)
 		},
 		func(i widget.TableCellID, o fyne.CanvasObject) {
 			o.(*canvas.Text).Text = newData[i.Row][i.Col]
@@ -81,20 +122,62 @@ func UpdateDetailInfo(newData [][]string, w fyne.Window, subContainer *fyne.Cont
 			}
 		},
 	)
+	newDetailInfo.OnSelected = func(id widget.TableCellID) {
+		newDetailInfo.Unselect(id)
+		if id.Row > 0 && onSelected != nil {
+			onSelected(id.Row - 1)
+		}
+	}
 	newDetailInfo.SetColumnWidth(0, 100)
-	newDetailInfo.SetColumnWidth(1, 100)
+	if len(newData) > 0 {
+		for i, header := range newData[0] {
+			if header == "Prop" {
+				newDetailInfo.SetColumnWidth(i, 240)
+			}
+		}
+	}
 
 	for i := 0; i < len(newData); i++ {
 		newDetailInfo.SetRowHeight(i, 30)
 	}
 
-	mainContainer := createMainContainer(subContainer, list, newDetailInfo)
+	mainContainer := createMainContainer(subContainer, gProfileHeader, list, newDetailInfo)
 	w.SetContent(mainContainer)
 }
 
 func main() {
-	// Create a new Fyne application instance.
-	a := app.New()
+	// Open the persistent cache so killmails and names survive restarts.
+	if cachePath, err := DefaultCachePath(); err != nil {
+		fmt.Printf("Error occurred: %v\n", err)
+	} else if cache, err := OpenDiskCache(cachePath); err != nil {
+		fmt.Printf("Error occurred: %v\n", err)
+	} else {
+		SetCache(cache)
+		defer cache.Close()
+	}
+
+	// Load the SDE so modules are classified by group rather than by name.
+	if sdePath, err := DefaultStaticDataPath(); err != nil {
+		fmt.Printf("Error occurred: %v\n", err)
+	} else if sdeDir, err := DefaultStaticDataImportDir(); err != nil {
+		fmt.Printf("Error occurred: %v\n", err)
+	} else if data, err := OpenStaticData(sdePath, sdeDir); err != nil {
+		fmt.Printf("Error occurred: %v\n", err)
+	} else {
+		SetStaticData(data)
+	}
+
+	// Create a new Fyne application instance, with an ID so preferences can be stored.
+	a := app.NewWithID(KAppID)
+
+	// Add the loss table columns of the rules file, then restore the columns picked in a previous session.
+	rulesPath, err := DefaultRulesPath()
+	if err != nil {
+		fmt.Printf("Error occurred: %v\n", err)
+	} else if err := ApplyRules(rulesPath); err != nil {
+		fmt.Printf("Error occurred: %v\n", err)
+	}
+	SetLossColumnChoices(a.Preferences().String(kLossColumnsPreference))
 
 	// Create a new window with the title "Go Eye".
 	w := a.NewWindow("Go Eye")
@@ -102,7 +185,7 @@ func main() {
 
 	// Initialize a data binding for the player name.
 	playerName := binding.NewString()
-	err := playerName.Set("")
+	err = playerName.Set("")
 	if err != nil {
 		fmt.Printf("Error occurred: %v\n", err)
 	}
@@ -110,17 +193,27 @@ func main() {
 	// Create widgets for player entry, search button, clipboard watcher switch, result list, and detail label.
 	playerEntry := createPlayerEntry(playerName)
 	searchButton := createSearchButton(playerName)
+	cancelButton := createCancelButton()
+	columnsButton := createColumnsButton(a.Preferences())
+	exportButton := createExportButton()
 	resultList, detailInfo := createResultWidgets()
 	gResultList = resultList
 
 	// Create sub-container for player entry, search button, and clipboard watcher switch.
-	subContainer := createInputContainer(playerEntry, searchButton)
+	subContainer := createInputContainer(playerEntry, searchButton, cancelButton, columnsButton, exportButton)
 	gSubContainer = subContainer
 
+	// Create the profile header once, its labels stay bound to the profile for the whole session.
+	profileHeader := createProfileHeader()
+	gProfileHeader = profileHeader
+
 	// Create the main container with a horizontal split for result list and detail label.
-	mainContainer := createMainContainer(subContainer, resultList, detailInfo)
+	mainContainer := createMainContainer(subContainer, profileHeader, resultList, detailInfo)
 
-	go InputWidgetWatcher(playerEntry, searchButton)
+	go InputWidgetWatcher(cancelButton)
+	if rulesPath != "" {
+		go WatchRules(rulesPath, KRulesPollInterval, refreshLossTable)
+	}
 
 	// Set the main container as the content of the window, resize it, and show the window.
 	w.SetContent(mainContainer)
@@ -131,8 +224,22 @@ func main() {
 
 var gWindow fyne.Window
 var gSubContainer *fyne.Container
+var gProfileHeader *fyne.Container
 var gResultList *widget.List
 
+// reportError prints the error and shows a user friendly description of it in a dialog.
+// Errors caused by cancelling an analysis are not reported.
+func reportError(err error) {
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	fmt.Printf("Error occurred: %v\n", err)
+	if gWindow != nil {
+		dialog.ShowError(errors.New(describeError(err)), gWindow)
+	}
+}
+
 // createPlayerEntry creates a widget for player name entry and binds it to the provided data binding.
 func createPlayerEntry(playerName binding.String) *widget.Entry {
 	playerEntry := widget.NewEntry()
@@ -157,58 +264,248 @@ var gSearchButton *widget.Button
 // createSearchButton creates a widget for the search button with the provided callback function.
 func createSearchButton(playerName binding.String) *widget.Button {
 	searchButton := widget.NewButton("Analyze", func() {
-		isWorking = true
+		ctx := startAnalysis()
+		go func() {
+			defer finishAnalysis()
 
-		playerTopShips.Set([]string{})
+			playerTopShips.Set([]string{})
+			playerProfile.Set("")
+			playerCorpHistory.Set("")
 
-		playerNameString, err := playerName.Get()
-		if err != nil {
-			isWorking = false
-			fmt.Printf("Error occurred: %v\n", err)
-			return
-		}
+			playerNameString, err := playerName.Get()
+			if err != nil {
+				fmt.Printf("Error occurred: %v\n", err)
+				return
+			}
+
+			playerID, err := ResolveNamesToCharacterIDs(ctx, []string{playerNameString})
+			if err != nil {
+				reportError(err)
+				return
+			}
+
+			if len(playerID) == 0 {
+				reportError(fmt.Errorf("no character named %q: %w", playerNameString, ErrNotFound))
+				return
+			}
+
+			// The profile is informative only, the analysis goes on without it.
+			profile, err := GetCharacterProfile(ctx, playerID[0])
+			if err != nil {
+				fmt.Printf("Error occurred: %v\n", err)
+			} else if ctx.Err() == nil {
+				playerProfile.Set(profile.Text())
+			}
+
+			history, err := GetCorporationHistory(ctx, playerID[0])
+			if err != nil {
+				fmt.Printf("Error occurred: %v\n", err)
+			} else if ctx.Err() == nil {
+				playerCorpHistory.Set(CorporationHistoryText(history, time.Now()))
+			}
+
+			ships, err := GetTopShips(ctx, playerID[0])
+			if err != nil {
+				reportError(err)
+				return
+			}
+
+			resolvedShips, err := ResolveIDsOrdered(ctx, ships)
+			if err != nil {
+				reportError(err)
+				return
+			}
+
+			// Ships unknown to ESI would show as blank rows, leave them out.
+			shipNames := make([]string, 0, len(resolvedShips))
+			for _, ship := range resolvedShips {
+				if ship.Resolved() && ship.Name != "" {
+					shipNames = append(shipNames, ship.Name)
+				}
+			}
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			// Set the pilot first, so a selection in the new list never pairs with the old one.
+			atomic.StoreInt64(&currentUser, int64(playerID[0]))
+			err = playerTopShips.Set(shipNames)
+			if err != nil {
+				fmt.Printf("Error occurred: %v\n", err)
+				return
+			}
+		}()
+	})
+
+	gSearchButton = searchButton
+	return searchButton
+}
+
+// kLossColumnsPreference is the preference key of the visible loss table columns.
+const kLossColumnsPreference = "lossColumns"
+
+// shownLossRows are the rows of the loss table on screen, kept to lay them out again when the columns change.
+var shownLossRows []LossRow
+var shownLossRowsMu sync.Mutex
+
+// showLossRows replaces the loss table with the given rows.
+func showLossRows(rows []LossRow) {
+	shownLossRowsMu.Lock()
+	shownLossRows = append([]LossRow(nil), rows...)
+	shownLossRowsMu.Unlock()
 
-		playerID, err := ResolveNamesToCharacterIDs([]string{playerNameString})
+	shown := append([]LossRow(nil), rows...)
+	UpdateDetailInfo(LossTable(rows), gWindow, gSubContainer, gResultList, func(row int) {
+		showFit(shown[row])
+	})
+}
+
+// showFit opens a dialog with the fit of a loss in EFT format, ready to be copied.
+func showFit(row LossRow) {
+	if !row.Resolved() {
+		dialog.ShowInformation("Fit", "The items of this loss are still being resolved.", gWindow)
+		return
+	}
+
+	fitText := widget.NewMultiLineEntry()
+	fitText.Wrapping = fyne.TextWrapOff
+
+	formats := make([]string, 0, len(FitFormats))
+	for _, format := range FitFormats {
+		formats = append(formats, string(format))
+	}
+	formatSelect := widget.NewSelect(formats, func(format string) {
+		text, err := ExportFit(FitFormat(format), row.NamedFit())
 		if err != nil {
-			isWorking = false
-			fmt.Printf("Error occurred: %v\n", err)
+			reportError(err)
 			return
 		}
+		fitText.SetText(text)
+	})
+	formatSelect.SetSelected(string(FitFormatEFT))
 
-		if len(playerID) == 0 {
-			isWorking = false
-			fmt.Printf("Error occurred: %v\n", fmt.Errorf("no character found"))
-			return
+	copyButton := widget.NewButton("Copy to clipboard", func() {
+		gWindow.Clipboard().SetContent(fitText.Text)
+	})
+
+	bottom := container.NewBorder(nil, nil, formatSelect, nil, copyButton)
+	content := container.NewBorder(nil, bottom, nil, nil, container.NewScroll(fitText))
+	fitDialog := dialog.NewCustom("Fit", "Close", content, gWindow)
+	fitDialog.Resize(fyne.NewSize(500, 380))
+	fitDialog.Show()
+}
+
+// resolvedLossFits returns the fits of the losses on screen whose items are known.
+func resolvedLossFits() []NamedFit {
+	shownLossRowsMu.Lock()
+	defer shownLossRowsMu.Unlock()
+
+	fits := make([]NamedFit, 0)
+	for _, row := range shownLossRows {
+		if row.Resolved() {
+			fits = append(fits, row.NamedFit())
 		}
+	}
+	return fits
+}
 
-		ships, err := GetTopShips(playerID[0])
-		if err != nil {
-			isWorking = false
-			fmt.Printf("Error occurred: %v\n", err)
+// createExportButton creates a widget for the button saving every fit of the loss table to a file.
+func createExportButton() *widget.Button {
+	return widget.NewButton("Export", func() {
+		fits := resolvedLossFits()
+		if len(fits) == 0 {
+			dialog.ShowInformation("Export", "There are no fits to export yet.", gWindow)
 			return
 		}
 
-		shipNames, err := ResolveIdsToNames(ships)
-		if err != nil {
-			isWorking = false
-			fmt.Printf("Error occurred: %v\n", err)
-			return
+		formats := make([]string, 0, len(FitFormats))
+		for _, format := range FitFormats {
+			formats = append(formats, string(format))
 		}
+		formatSelect := widget.NewSelect(formats, nil)
+		formatSelect.SetSelected(string(FitFormatEFT))
 
-		err = playerTopShips.Set(shipNames)
-		if err != nil {
-			isWorking = false
-			fmt.Printf("Error occurred: %v\n", err)
-			return
+		dialog.ShowCustomConfirm(fmt.Sprintf("Export %d fits", len(fits)), "Save", "Cancel", formatSelect, func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+			format := FitFormat(formatSelect.Selected)
+
+			saveDialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+				if err != nil {
+					reportError(err)
+					return
+				}
+				if writer == nil {
+					return
+				}
+				// Some storages only commit the file on close, so its error matters too.
+				err = ExportFits(writer, format, fits)
+				if closeErr := writer.Close(); err == nil {
+					err = closeErr
+				}
+				if err != nil {
+					reportError(err)
+				}
+			}, gWindow)
+			saveDialog.SetFileName("fits" + format.Extension())
+			saveDialog.Show()
+		}, gWindow)
+	})
+}
+
+// refreshLossTable lays the loss table on screen out again for the current columns.
+func refreshLossTable() {
+	shownLossRowsMu.Lock()
+	rows := append([]LossRow(nil), shownLossRows...)
+	shownLossRowsMu.Unlock()
+
+	if gWindow == nil {
+		return
+	}
+	RefreshLossRows(rows)
+	showLossRows(rows)
+}
+
+// createColumnsButton creates a widget for the button picking the loss table columns, saving the choice in prefs.
+func createColumnsButton(prefs fyne.Preferences) *widget.Button {
+	return widget.NewButton("Columns", func() {
+		visible := make(map[string]bool)
+		for _, id := range VisibleLossColumnIDs() {
+			visible[id] = true
 		}
 
-		currentUser = playerID[0]
+		grid := container.NewGridWithColumns(4)
+		for _, column := range LossColumns() {
+			id := column.ID
+			check := widget.NewCheck(column.Header, nil)
+			check.SetChecked(visible[id])
+			check.OnChanged = func(checked bool) {
+				visible[id] = checked
+
+				ids := make([]string, 0)
+				for _, column := range LossColumns() {
+					if visible[column.ID] {
+						ids = append(ids, column.ID)
+					}
+				}
+				SetVisibleLossColumns(ids)
+				prefs.SetString(kLossColumnsPreference, LossColumnChoices())
+				refreshLossTable()
+			}
+			grid.Add(check)
+		}
 
-		isWorking = false
+		dialog.ShowCustom("Loss table columns", "Close", grid, gWindow)
 	})
+}
 
-	gSearchButton = searchButton
-	return searchButton
+// createCancelButton creates a widget for the button that aborts the analysis in progress.
+func createCancelButton() *widget.Button {
+	cancelButton := widget.NewButton("Cancel", CancelAnalysis)
+	cancelButton.Disable()
+	return cancelButton
 }
 
 // createResultWidgets creates widgets for the result list and detail label.
@@ -223,140 +520,80 @@ func createResultWidgets() (*widget.List, *widget.Table) {
 		},
 	)
 	resultList.OnSelected = func(id int) {
-		isWorking = true
-
-		shipSelected, err := playerTopShips.Get()
-		if err != nil {
-			isWorking = false
-			fmt.Printf("Error occurred: %v\n", err)
-			return
-		}
-
-		shipID, err := ResolveItemNamesToIDs([]string{shipSelected[id]})
-		if err != nil {
-			isWorking = false
-			fmt.Printf("Error occurred: %v\n", err)
-			return
-		}
-
-		kms, err := GetRecentLosses(currentUser, shipID[0])
-		if err != nil {
-			isWorking = false
-			fmt.Printf("Error occurred: %v\n", err)
-			return
-		}
+		ctx := startAnalysis()
+		go func() {
+			defer finishAnalysis()
 
-		newData := [][]string{
-			{"Date", "Prop", "Scram", "Point", "Web", "Neut", "Damp"},
-		}
-		for _, km := range kms {
-			items, t, err := GetItemsFromKillmail(km.KillmailID, km.ZKB.Hash)
+			shipSelected, err := playerTopShips.Get()
 			if err != nil {
 				fmt.Printf("Error occurred: %v\n", err)
-				continue
+				return
 			}
-
-			var prop, scram, point, web, neut, damp int
-
-			itemsInString, err := ResolveIdsToNames(items)
-			if err != nil {
-				fmt.Printf("Error occurred: %v\n", err)
-				continue
+			if id < 0 || id >= len(shipSelected) {
+				// A new search replaced the list since the click.
+				return
 			}
 
-			var date string
-			if time.Now().Sub(t).Hours() < 24*31 {
-				date = fmt.Sprintf("%v days ago", int(time.Now().Sub(t).Hours()/24))
-			} else {
-				date = fmt.Sprintf("%v", t.Format("2006-01-02"))
+			shipID, err := ResolveItemNamesToIDs(ctx, []string{shipSelected[id]})
+			if err != nil {
+				reportError(err)
+				return
 			}
 
-			for _, item := range itemsInString {
-				item = strings.ToLower(item)
-				if strings.Contains(item, "1mn") {
-					prop = 1
-				} else if strings.Contains(item, "5mn") {
-					prop = 5
-				} else if strings.Contains(item, "10mn") {
-					prop = 10
-				} else if strings.Contains(item, "50mn") {
-					prop = 50
-				} else if strings.Contains(item, "100mn") {
-					prop = 100
-				} else if strings.Contains(item, "500mn") {
-					prop = 500
-				}
-
-				if strings.Contains(item, "warp scrambler") {
-					scram += 1
-				}
-
-				if strings.Contains(item, "warp disruptor") {
-					point += 1
-				}
-
-				if strings.Contains(item, "stasis web") {
-					web += 1
-				}
-
-				if strings.Contains(item, "energy neutralizer") {
-					neut += 1
-				}
-
-				if strings.Contains(item, "sensor dampener") {
-					damp += 1
-				}
+			kms, err := GetRecentLosses(ctx, int(atomic.LoadInt64(&currentUser)), shipID[0])
+			if err != nil {
+				reportError(err)
+				return
 			}
 
-			line := []string{
-				date,
+			// Clear the previous pilot's table and list the losses as the killmails arrive.
+			killmails := make([]Killmail, 0)
+			rows := make([]LossRow, 0)
+			if ctx.Err() != nil {
+				return
 			}
-			if prop > 0 {
-				if prop == 1 || prop == 10 || prop == 100 {
-					line = append(line, fmt.Sprintf("%vMN AB", prop))
-				} else {
-					line = append(line, fmt.Sprintf("%vMN MWD", prop))
+			showLossRows(rows)
+
+			fetchCtx, stopFetching := context.WithCancel(ctx)
+			defer stopFetching()
+
+			for result := range FetchKillmails(fetchCtx, kms, KKillmailWorkers) {
+				if errors.Is(result.Err, ErrRateLimited) || errors.Is(result.Err, ErrUpstreamDown) {
+					// The remaining killmails would fail the same way, stop here.
+					reportError(result.Err)
+					stopFetching()
+					continue
+				} else if result.Err != nil {
+					fmt.Printf("Error occurred: %v\n", result.Err)
+					continue
 				}
-			} else {
-				line = append(line, "X")
-			}
 
-			if scram > 0 {
-				line = append(line, "O")
-			} else {
-				line = append(line, "X")
-			}
-
-			if point > 0 {
-				line = append(line, "O")
-			} else {
-				line = append(line, "X")
+				killmails = append(killmails, result.Killmail)
+				rows = append(rows, pendingLossRow(result.Killmail))
+				SortLossRows(rows)
+				if ctx.Err() != nil {
+					return
+				}
+				showLossRows(rows)
 			}
 
-			if web > 0 {
-				line = append(line, strconv.Itoa(web))
-			} else {
-				line = append(line, "X")
+			// Resolve the items of every loss at once, then fill in the table.
+			names, err := ResolveKillmailTypes(ctx, killmails)
+			if err != nil {
+				reportError(err)
+				return
 			}
 
-			if neut > 0 {
-				line = append(line, "O")
-			} else {
-				line = append(line, "X")
+			rows = rows[:0]
+			for _, killmail := range killmails {
+				rows = append(rows, summarizeLoss(killmail, names))
 			}
-
-			if damp > 0 {
-				line = append(line, "O")
-			} else {
-				line = append(line, "X")
+			SortLossRows(rows)
+			if ctx.Err() != nil {
+				return
 			}
-
-			newData = append(newData, line)
-		}
-
-		fmt.Println(newData)
-		UpdateDetailInfo(newData, gWindow, gSubContainer, gResultList)
-		isWorking = false
+			showLossRows(rows)
+		}()
 	}
 
 	detailInfo := widget.NewTable(
@@ -375,9 +612,9 @@ func createResultWidgets() (*widget.List, *widget.Table) {
 	return resultList, detailInfo
 }
 
-// createInputContainer creates a container for player entry, search button, and clipboard watcher switch.
-func createInputContainer(playerEntry *widget.Entry, searchButton *widget.Button) *fyne.Container {
-	miscContainer := container.NewHBox(searchButton)
+// createInputContainer creates a container for player entry, search button, cancel button, columns button, and export button.
+func createInputContainer(playerEntry *widget.Entry, searchButton *widget.Button, cancelButton *widget.Button, columnsButton *widget.Button, exportButton *widget.Button) *fyne.Container {
+	miscContainer := container.NewHBox(searchButton, cancelButton, columnsButton, exportButton)
 	return container.New(
 		layout.NewBorderLayout(nil, nil, nil, miscContainer),
 		playerEntry,
@@ -385,9 +622,21 @@ func createInputContainer(playerEntry *widget.Entry, searchButton *widget.Button
 	)
 }
 
-// createMainContainer creates the main container with a horizontal split for result list and detail label.
-func createMainContainer(subContainer *fyne.Container, resultList *widget.List, detailInfo *widget.Table) *fyne.Container {
-	resultContainer := container.NewHSplit(resultList, detailInfo)
+// createProfileHeader creates the labels showing the analyzed pilot's profile and corporation history.
+func createProfileHeader() *fyne.Container {
+	profileLabel := widget.NewLabelWithData(playerProfile)
+	profileLabel.Wrapping = fyne.TextWrapWord
+
+	historyLabel := widget.NewLabelWithData(playerCorpHistory)
+	historyLabel.Wrapping = fyne.TextWrapWord
+
+	return container.NewVBox(profileLabel, historyLabel)
+}
+
+// createMainContainer creates the main container with a horizontal split for the profile and result list, and the detail label.
+func createMainContainer(subContainer *fyne.Container, profileHeader *fyne.Container, resultList *widget.List, detailInfo *widget.Table) *fyne.Container {
+	pilotContainer := container.NewBorder(profileHeader, nil, nil, nil, resultList)
+	resultContainer := container.NewHSplit(pilotContainer, detailInfo)
 	resultContainer.SetOffset(0.3)
 
 	return container.New(layout.NewBorderLayout(subContainer, nil, nil, nil), subContainer, resultContainer)
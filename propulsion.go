@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SDE meta group IDs, telling apart the variants of a module.
+const (
+	MetaGroupTech1     = 1
+	MetaGroupTech2     = 2
+	MetaGroupStoryline = 3
+	MetaGroupFaction   = 4
+	MetaGroupOfficer   = 5
+	MetaGroupDeadspace = 6
+)
+
+var metaGroupNames = map[int]string{
+	MetaGroupTech1:     "T1",
+	MetaGroupTech2:     "T2",
+	MetaGroupStoryline: "Storyline",
+	MetaGroupFaction:   "Faction",
+	MetaGroupOfficer:   "Officer",
+	MetaGroupDeadspace: "Deadspace",
+}
+
+// Propulsion is a single propulsion module fitted to a ship.
+type Propulsion struct {
+	Kind     ModuleKind
+	SizeMN   int
+	HullSize string
+	// MetaGroupID and MetaLevel are zero when the SDE is not loaded.
+	MetaGroupID int
+	MetaLevel   int
+}
+
+// String formats the module as it is shown in the loss table, e.g. "5MN MWD" or "Large MJD".
+// Variants better than tech II are marked, as they are noticeably faster.
+func (p Propulsion) String() string {
+	var text string
+	switch p.Kind {
+	case KindAfterburner:
+		text = fmt.Sprintf("%vMN AB", p.SizeMN)
+	case KindMicrowarpdrive:
+		text = fmt.Sprintf("%vMN MWD", p.SizeMN)
+	case KindMicroJumpDrive:
+		text = strings.TrimSpace(p.HullSize + " MJD")
+	case KindMicroJumpFieldGenerator:
+		text = strings.TrimSpace(p.HullSize + " MJFG")
+	default:
+		return p.Kind.String()
+	}
+
+	if p.MetaGroupID > MetaGroupTech2 {
+		text += fmt.Sprintf(" (%s)", metaGroupNames[p.MetaGroupID])
+	}
+	return text
+}
+
+// PropulsionFit is every propulsion module of a fit.
+type PropulsionFit struct {
+	Modules []Propulsion
+}
+
+// ClassifyPropulsion picks the propulsion modules out of the roles of a fit. They are ordered
+// with speed modules first, microwarpdrives before afterburners, then by size.
+func ClassifyPropulsion(roles []ModuleRole) PropulsionFit {
+	modules := make([]Propulsion, 0)
+	for _, role := range roles {
+		if !role.Kind.Propulsion() {
+			continue
+		}
+		modules = append(modules, Propulsion{
+			Kind:        role.Kind,
+			SizeMN:      role.SizeMN,
+			HullSize:    role.HullSize,
+			MetaGroupID: role.MetaGroupID,
+			MetaLevel:   role.MetaLevel,
+		})
+	}
+
+	order := map[ModuleKind]int{
+		KindMicrowarpdrive:          0,
+		KindAfterburner:             1,
+		KindMicroJumpDrive:          2,
+		KindMicroJumpFieldGenerator: 3,
+	}
+	sort.SliceStable(modules, func(i, j int) bool {
+		if modules[i].Kind != modules[j].Kind {
+			return order[modules[i].Kind] < order[modules[j].Kind]
+		}
+		if modules[i].SizeMN != modules[j].SizeMN {
+			return modules[i].SizeMN > modules[j].SizeMN
+		}
+		return modules[i].MetaLevel > modules[j].MetaLevel
+	})
+
+	return PropulsionFit{Modules: modules}
+}
+
+// Has reports whether a module of the given kind is fitted.
+func (f PropulsionFit) Has(kind ModuleKind) bool {
+	for _, module := range f.Modules {
+		if module.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// DualProp reports whether both an afterburner and a microwarpdrive are fitted.
+func (f PropulsionFit) DualProp() bool {
+	return f.Has(KindAfterburner) && f.Has(KindMicrowarpdrive)
+}
+
+// String joins the fitted propulsion modules, "X" when there are none.
+func (f PropulsionFit) String() string {
+	if len(f.Modules) == 0 {
+		return "X"
+	}
+
+	texts := make([]string, 0, len(f.Modules))
+	for _, module := range f.Modules {
+		texts = append(texts, module.String())
+	}
+
+	text := strings.Join(texts, " + ")
+	if f.DualProp() {
+		text = "Dual prop: " + text
+	}
+	return text
+}
@@ -0,0 +1,50 @@
+package main
+
+import "testing"
+
+func TestClassifyPropulsion(t *testing.T) {
+	previous := staticData
+	SetStaticData(nil)
+	defer SetStaticData(previous)
+
+	tests := []struct {
+		names    []string
+		expected string
+		dual     bool
+	}{
+		{[]string{"10MN Afterburner II"}, "10MN AB", false},
+		{[]string{"100MN Afterburner I"}, "100MN AB", false},
+		{[]string{"1MN Afterburner II", "5MN Quad LiF Restrained Microwarpdrive"}, "Dual prop: 5MN MWD + 1MN AB", true},
+		{[]string{"Large Micro Jump Drive", "500MN Microwarpdrive II"}, "500MN MWD + Large MJD", false},
+		{[]string{"Large Micro Jump Field Generator"}, "Large MJFG", false},
+		{[]string{"Damage Control II"}, "X", false},
+	}
+	for _, test := range tests {
+		roles := make([]ModuleRole, 0)
+		for i, name := range test.names {
+			roles = append(roles, ClassifyModule(i, name))
+		}
+
+		prop := ClassifyPropulsion(roles)
+		if prop.String() != test.expected || prop.DualProp() != test.dual {
+			t.Errorf("Expected %q for %v, got: %q", test.expected, test.names, prop.String())
+		}
+	}
+}
+
+func TestClassifyPropulsionFromSDE(t *testing.T) {
+	data := testStaticData()
+	data.Types[5973] = sdeType{Name: "Coreli A-Type 5MN Microwarpdrive", GroupID: GroupPropulsionModule}
+	data.Attributes[5973] = map[int]float64{AttributeMassAddition: 500000, AttributeSignatureRadiusBonus: 500, AttributeMetaGroup: MetaGroupDeadspace, AttributeMetaLevel: 14}
+	data.Types[4383] = sdeType{Name: "Large Micro Jump Drive", GroupID: GroupMicroJumpDrive}
+
+	previous := staticData
+	SetStaticData(data)
+	defer SetStaticData(previous)
+
+	roles := []ModuleRole{ClassifyModule(4383, ""), ClassifyModule(12058, ""), ClassifyModule(5973, "")}
+	prop := ClassifyPropulsion(roles)
+	if prop.String() != "Dual prop: 5MN MWD (Deadspace) + 10MN AB + Large MJD" {
+		t.Errorf("Unexpected propulsion: %q", prop.String())
+	}
+}
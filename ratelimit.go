@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimiter paces outgoing requests to a service and keeps track of the ESI error budget.
+type RateLimiter struct {
+	mu             sync.Mutex
+	interval       time.Duration
+	next           time.Time
+	errorsRemain   int
+	errorsReset    time.Time
+	errorThreshold int
+}
+
+// NewRateLimiter creates a RateLimiter that lets one request through per interval.
+func NewRateLimiter(interval time.Duration) *RateLimiter {
+	return &RateLimiter{
+		interval:       interval,
+		errorsRemain:   -1,
+		errorThreshold: KESIErrorLimitThreshold,
+	}
+}
+
+// esiRateLimiter is shared by every ESI client so concurrent lookups spend the same error budget.
+var esiRateLimiter = NewRateLimiter(KESIMinInterval)
+
+// zkillRateLimiter is shared by every zKillboard client.
+var zkillRateLimiter = NewRateLimiter(KZKillMinInterval)
+
+// Wait blocks until the next request is allowed to go out or the context is done.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return ctx.Err()
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next
+	if l.errorsRemain >= 0 && l.errorsRemain <= l.errorThreshold && l.errorsReset.After(at) {
+		// The error budget is nearly spent, hold everything until ESI resets the window.
+		at = l.errorsReset
+	}
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	return sleepContext(ctx, at.Sub(now))
+}
+
+// ErrorBudgetLow reports whether the last response left the ESI error budget at or below the threshold.
+func (l *RateLimiter) ErrorBudgetLow() bool {
+	if l == nil {
+		return false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.errorsRemain >= 0 && l.errorsRemain <= l.errorThreshold && l.errorsReset.After(time.Now())
+}
+
+// Observe reads the ESI error limit headers from a response.
+func (l *RateLimiter) Observe(resp *http.Response) {
+	if l == nil {
+		return
+	}
+
+	remain, err := strconv.Atoi(resp.Header.Get("X-ESI-Error-Limit-Remain"))
+	if err != nil {
+		return
+	}
+	reset, err := strconv.Atoi(resp.Header.Get("X-ESI-Error-Limit-Reset"))
+	if err != nil {
+		return
+	}
+
+	l.mu.Lock()
+	l.errorsRemain = remain
+	l.errorsReset = time.Now().Add(time.Duration(reset) * time.Second)
+	l.mu.Unlock()
+}
+
+// Pause holds back every request until the given duration has passed.
+func (l *RateLimiter) Pause(d time.Duration) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	if until := time.Now().Add(d); until.After(l.next) {
+		l.next = until
+	}
+	l.mu.Unlock()
+}
+
+// sleepContext pauses for d, returning early with the context error if the context is done first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// RetryPolicy describes how failed idempotent requests are retried.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// DefaultRetryPolicy is used by the clients unless configured otherwise.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: KMaxRetryAttempts,
+	BaseDelay:   KRetryBaseDelay,
+	MaxDelay:    KRetryMaxDelay,
+}
+
+var jitterRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+var jitterMu sync.Mutex
+
+// backoff returns an exponential delay with full jitter for the given attempt, starting at zero.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay << uint(attempt)
+	if delay <= 0 || delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+
+	jitterMu.Lock()
+	defer jitterMu.Unlock()
+	return delay/2 + time.Duration(jitterRand.Int63n(int64(delay/2)+1))
+}
+
+// isRetryableStatus reports whether a response status is worth retrying.
+func isRetryableStatus(status int) bool {
+	return status == 420 || status == http.StatusTooManyRequests || status >= 500
+}
+
+// isIdempotent reports whether the request can safely be sent more than once.
+func isIdempotent(req *http.Request) bool {
+	return req.Method == "GET" || req.Method == "HEAD"
+}
+
+// retryAfter returns how long the server asked us to wait, if it did.
+func retryAfter(resp *http.Response) time.Duration {
+	for _, header := range []string{"Retry-After", "X-ESI-Error-Limit-Reset"} {
+		if seconds, err := strconv.Atoi(resp.Header.Get(header)); err == nil && seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	return 0
+}
+
+// doWithRetry sends the request through the rate limiter and retries idempotent requests on throttling or server errors.
+// Waiting between attempts stops as soon as the request context is done. Requests that never got a response,
+// for example because the host could not be resolved or reached, fail with ErrUpstreamDown.
+func doWithRetry(client *http.Client, limiter *RateLimiter, policy RetryPolicy, req *http.Request) (*http.Response, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	attempts := policy.MaxAttempts
+	if attempts < 1 || !isIdempotent(req) {
+		attempts = 1
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := limiter.Wait(req.Context()); err != nil {
+			return nil, err
+		}
+
+		resp, err := client.Do(req)
+		last := attempt+1 >= attempts
+		if err != nil {
+			if req.Context().Err() != nil {
+				return nil, err
+			}
+			if last {
+				return nil, fmt.Errorf("%w: %v", ErrUpstreamDown, err)
+			}
+			if err := sleepContext(req.Context(), policy.backoff(attempt)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		limiter.Observe(resp)
+		if !isRetryableStatus(resp.StatusCode) || last {
+			return resp, nil
+		}
+
+		delay := policy.backoff(attempt)
+		if resp.StatusCode == 420 || resp.StatusCode == http.StatusTooManyRequests {
+			if wait := retryAfter(resp); wait > delay {
+				delay = wait
+			}
+			// Throttling applies to every caller, not only this request.
+			limiter.Pause(delay)
+		}
+
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+		if err := sleepContext(req.Context(), delay); err != nil {
+			return nil, err
+		}
+	}
+}
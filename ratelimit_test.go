@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   time.Millisecond,
+	MaxDelay:    5 * time.Millisecond,
+}
+
+func TestDoWithRetryRecoversFromServerErrors(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	resp, err := doWithRetry(server.Client(), NewRateLimiter(0), testRetryPolicy, req)
+	if err != nil {
+		t.Errorf("Error occurred: %v", err)
+		return
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || calls != 3 {
+		t.Errorf("Expected success after 3 calls, got status %d after %d calls", resp.StatusCode, calls)
+	}
+}
+
+func TestDoWithRetryDoesNotRepeatPost(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	req, _ := http.NewRequest("POST", server.URL, strings.NewReader("[]"))
+	resp, err := doWithRetry(server.Client(), NewRateLimiter(0), testRetryPolicy, req)
+	if err != nil {
+		t.Errorf("Error occurred: %v", err)
+		return
+	}
+	resp.Body.Close()
+
+	if calls != 1 {
+		t.Errorf("Expected 1 call, got: %d", calls)
+	}
+}
+
+func TestRateLimiterHoldsWhenErrorBudgetIsLow(t *testing.T) {
+	limiter := NewRateLimiter(0)
+
+	resp := &http.Response{Header: http.Header{}}
+	resp.Header.Set("X-ESI-Error-Limit-Remain", "100")
+	resp.Header.Set("X-ESI-Error-Limit-Reset", "30")
+	limiter.Observe(resp)
+
+	start := time.Now()
+	limiter.Wait(context.Background())
+	if time.Since(start) > 100*time.Millisecond {
+		t.Errorf("Limiter waited although the error budget is healthy")
+	}
+
+	resp.Header.Set("X-ESI-Error-Limit-Remain", "5")
+	limiter.Observe(resp)
+
+	limiter.mu.Lock()
+	holdUntil := limiter.errorsReset
+	limiter.mu.Unlock()
+	if time.Until(holdUntil) < 29*time.Second {
+		t.Errorf("Expected the limiter to hold until the error window resets, got: %v", holdUntil)
+	}
+}
+
+func TestRateLimiterWaitsForErrorWindowReset(t *testing.T) {
+	limiter := NewRateLimiter(0)
+
+	resp := &http.Response{Header: http.Header{}}
+	resp.Header.Set("X-ESI-Error-Limit-Remain", "5")
+	resp.Header.Set("X-ESI-Error-Limit-Reset", "1")
+	limiter.Observe(resp)
+
+	// A deadline before the reset cuts the wait short.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the limiter to hold past the deadline, got: %v", err)
+	}
+
+	// A deadline after the reset lets the request through once the window has reset.
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	if err := limiter.Wait(ctx); err != nil {
+		t.Errorf("Error occurred: %v", err)
+	}
+	if waited := time.Since(start); waited < 800*time.Millisecond || waited > 3*time.Second {
+		t.Errorf("Expected the limiter to hold until the error window resets, waited: %v", waited)
+	}
+}
+
+func TestDoWithRetryStopsOnCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+	start := time.Now()
+	_, err := doWithRetry(server.Client(), NewRateLimiter(0), testRetryPolicy, req)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected %v, got: %v", context.Canceled, err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("Expected the retry wait to stop on cancel, took: %v", time.Since(start))
+	}
+}
+
+func TestDoWithRetryReportsUnreachableHost(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := server.URL
+	server.Close()
+
+	req, _ := http.NewRequest("GET", url, nil)
+	_, err := doWithRetry(&http.Client{}, NewRateLimiter(0), testRetryPolicy, req)
+	if !errors.Is(err, ErrUpstreamDown) {
+		t.Errorf("Expected %v, got: %v", ErrUpstreamDown, err)
+	}
+}
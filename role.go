@@ -0,0 +1,403 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ModuleKind is what a fitted module does in a fight.
+type ModuleKind int
+
+const (
+	KindOther ModuleKind = iota
+	KindAfterburner
+	KindMicrowarpdrive
+	KindMicroJumpDrive
+	KindMicroJumpFieldGenerator
+	KindWarpScrambler
+	KindWarpDisruptor
+	KindStasisWeb
+	KindStasisGrappler
+	KindEnergyNeutralizer
+	KindEnergyNosferatu
+	KindSensorDampener
+	KindECM
+	KindTrackingDisruptor
+	KindGuidanceDisruptor
+	KindTargetPainter
+	KindWarpDisruptFieldGenerator
+	KindInterdictionSphereLauncher
+	KindHeavyWarpScrambler
+	KindHeavyWarpDisruptor
+	KindCloak
+	KindCynosuralField
+	KindRemoteArmorRepairer
+	KindRemoteShieldBooster
+	KindAncillaryShieldBooster
+	KindAncillaryArmorRepairer
+	KindWarpCoreStabilizer
+)
+
+var moduleKindNames = map[ModuleKind]string{
+	KindOther:                      "Other",
+	KindAfterburner:                "Afterburner",
+	KindMicrowarpdrive:             "Microwarpdrive",
+	KindMicroJumpDrive:             "Micro Jump Drive",
+	KindMicroJumpFieldGenerator:    "Micro Jump Field Generator",
+	KindWarpScrambler:              "Warp Scrambler",
+	KindWarpDisruptor:              "Warp Disruptor",
+	KindStasisWeb:                  "Stasis Webifier",
+	KindStasisGrappler:             "Stasis Grappler",
+	KindEnergyNeutralizer:          "Energy Neutralizer",
+	KindEnergyNosferatu:            "Energy Nosferatu",
+	KindSensorDampener:             "Sensor Dampener",
+	KindECM:                        "ECM",
+	KindTrackingDisruptor:          "Tracking Disruptor",
+	KindGuidanceDisruptor:          "Guidance Disruptor",
+	KindTargetPainter:              "Target Painter",
+	KindWarpDisruptFieldGenerator:  "Warp Disruption Field Generator",
+	KindInterdictionSphereLauncher: "Interdiction Sphere Launcher",
+	KindHeavyWarpScrambler:         "Heavy Warp Scrambler",
+	KindHeavyWarpDisruptor:         "Heavy Warp Disruptor",
+	KindCloak:                      "Cloaking Device",
+	KindCynosuralField:             "Cynosural Field Generator",
+	KindRemoteArmorRepairer:        "Remote Armor Repairer",
+	KindRemoteShieldBooster:        "Remote Shield Booster",
+	KindAncillaryShieldBooster:     "Ancillary Shield Booster",
+	KindAncillaryArmorRepairer:     "Ancillary Armor Repairer",
+	KindWarpCoreStabilizer:         "Warp Core Stabilizer",
+}
+
+func (k ModuleKind) String() string {
+	if name, ok := moduleKindNames[k]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
+// MarshalText writes the kind by name, as used in the rules file.
+func (k ModuleKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText reads a kind by name, ignoring case.
+func (k *ModuleKind) UnmarshalText(text []byte) error {
+	for kind, name := range moduleKindNames {
+		if strings.EqualFold(name, string(text)) {
+			*k = kind
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown module kind %q", text)
+}
+
+// Propulsion reports whether the kind moves the ship it is fitted to.
+func (k ModuleKind) Propulsion() bool {
+	return k == KindAfterburner || k == KindMicrowarpdrive || k == KindMicroJumpDrive || k == KindMicroJumpFieldGenerator
+}
+
+// SDE group IDs of the modules the classifier recognizes.
+const (
+	GroupRemoteShieldBooster        = 41
+	GroupPropulsionModule           = 46
+	GroupWarpScrambler              = 52
+	GroupStasisWeb                  = 65
+	GroupEnergyNosferatu            = 68
+	GroupEnergyNeutralizer          = 71
+	GroupECM                        = 201
+	GroupSensorDampener             = 208
+	GroupWeaponDisruptor            = 291
+	GroupWarpCoreStabilizer         = 315
+	GroupRemoteArmorRepairer        = 325
+	GroupCloakingDevice             = 330
+	GroupTargetPainter              = 379
+	GroupInterdictionSphereLauncher = 589
+	GroupCynosuralFieldGenerator    = 658
+	GroupHeavyInterdictionCruiser   = 894
+	GroupWarpDisruptFieldGenerator  = 899
+	GroupAncillaryShieldBooster     = 1156
+	GroupMicroJumpDrive             = 1189
+	GroupAncillaryArmorRepairer     = 1199
+	GroupMicroJumpFieldGenerator    = 1533
+	GroupStasisGrappler             = 1672
+)
+
+// Dogma attribute IDs the classifier reads.
+const (
+	AttributeSpeedFactor             = 20
+	AttributeMaxRange                = 54
+	AttributeEnergyNeutralizerAmount = 97
+	AttributeWarpScrambleStrength    = 105
+	AttributeMaxTargetRangeBonus     = 309
+	AttributeMissileVelocityBonus    = 547
+	AttributeSignatureRadiusBonus    = 554
+	AttributeScanResolutionBonus     = 566
+	AttributeMassAddition            = 796
+	AttributeCanFitShipGroup01       = 1298
+)
+
+// kWarpScramblerMaxRange separates scramblers from disruptors, which share a group. Every
+// scrambler variant reaches less far, every disruptor variant further.
+const kWarpScramblerMaxRange = 16000
+
+var groupKinds = map[int]ModuleKind{
+	GroupStasisWeb:                  KindStasisWeb,
+	GroupStasisGrappler:             KindStasisGrappler,
+	GroupEnergyNeutralizer:          KindEnergyNeutralizer,
+	GroupEnergyNosferatu:            KindEnergyNosferatu,
+	GroupSensorDampener:             KindSensorDampener,
+	GroupECM:                        KindECM,
+	GroupTargetPainter:              KindTargetPainter,
+	GroupWarpDisruptFieldGenerator:  KindWarpDisruptFieldGenerator,
+	GroupInterdictionSphereLauncher: KindInterdictionSphereLauncher,
+	GroupCloakingDevice:             KindCloak,
+	GroupCynosuralFieldGenerator:    KindCynosuralField,
+	GroupRemoteArmorRepairer:        KindRemoteArmorRepairer,
+	GroupRemoteShieldBooster:        KindRemoteShieldBooster,
+	GroupAncillaryShieldBooster:     KindAncillaryShieldBooster,
+	GroupAncillaryArmorRepairer:     KindAncillaryArmorRepairer,
+	GroupWarpCoreStabilizer:         KindWarpCoreStabilizer,
+}
+
+// ModuleRole is what a fitted type does, along with the strength of its effect.
+type ModuleRole struct {
+	TypeID int
+	Name   string
+	// GroupID is zero when the role was guessed from the type name.
+	GroupID int
+	Kind    ModuleKind
+	// SizeMN is the nominal size of a propulsion module, e.g. 5 for a 5MN Microwarpdrive.
+	SizeMN int
+	// HullSize is the hull class a propulsion module is made for, one of the Hull* constants.
+	HullSize string
+	// MetaGroupID tells tech I, tech II, faction, deadspace and officer variants apart.
+	MetaGroupID int
+	MetaLevel   int
+	// ScrambleStrength is the warp scramble strength of points and scramblers.
+	ScrambleStrength float64
+	// SpeedFactor is the velocity change in percent, negative for webs.
+	SpeedFactor float64
+	// NeutAmount is the capacitor drained per cycle by neutralizers and nosferatus.
+	NeutAmount float64
+	// DampTargetRange and DampScanRes are the targeting range and scan resolution changes of
+	// sensor dampeners in percent, negative as they reduce the target's sensors.
+	DampTargetRange float64
+	DampScanRes     float64
+	// Range is the optimal range in meters.
+	Range float64
+	// FromSDE is false when the role was guessed from the type name, without an SDE.
+	FromSDE bool
+}
+
+// ClassifyModule returns the role of a fitted type. The SDE group and dogma attributes are
+// used when the type is known, so every faction, deadspace and officer variant is counted.
+// Otherwise the role is guessed from the type name.
+func ClassifyModule(typeID int, name string) ModuleRole {
+	info, ok := LookupType(typeID)
+	if !ok {
+		return classifyModuleByName(typeID, name)
+	}
+	return classifyModuleByType(info)
+}
+
+// ClassifyModules returns the role of every fitted module of a fit, including those of KindOther
+// so rules can still match them by type, group or name.
+func ClassifyModules(fit Fit, names map[int]ResolvedName) []ModuleRole {
+	roles := make([]ModuleRole, 0)
+	for _, id := range fit.ModuleTypeIDs() {
+		roles = append(roles, ClassifyModule(id, names[id].Name))
+	}
+	return roles
+}
+
+func classifyModuleByType(info TypeInfo) ModuleRole {
+	role := ModuleRole{TypeID: info.TypeID, Name: info.Name, GroupID: info.GroupID, MetaLevel: info.MetaLevel(), FromSDE: true}
+	if metaGroup, ok := info.Attribute(AttributeMetaGroup); ok {
+		role.MetaGroupID = int(metaGroup)
+	}
+	role.ScrambleStrength, _ = info.Attribute(AttributeWarpScrambleStrength)
+	role.SpeedFactor, _ = info.Attribute(AttributeSpeedFactor)
+	role.NeutAmount, _ = info.Attribute(AttributeEnergyNeutralizerAmount)
+	role.DampTargetRange, _ = info.Attribute(AttributeMaxTargetRangeBonus)
+	role.DampScanRes, _ = info.Attribute(AttributeScanResolutionBonus)
+	role.Range, _ = info.Attribute(AttributeMaxRange)
+
+	switch info.GroupID {
+	case GroupPropulsionModule:
+		massAddition, _ := info.Attribute(AttributeMassAddition)
+		// Only microwarpdrives bloom the signature radius.
+		if _, ok := info.Attribute(AttributeSignatureRadiusBonus); ok {
+			role.Kind = KindMicrowarpdrive
+		} else {
+			role.Kind = KindAfterburner
+		}
+		role.SizeMN = propulsionSize(role.Kind, massAddition)
+		role.HullSize = hullSizeForMN(role.SizeMN)
+	case GroupMicroJumpDrive, GroupMicroJumpFieldGenerator:
+		role.Kind = KindMicroJumpDrive
+		if info.GroupID == GroupMicroJumpFieldGenerator || strings.Contains(strings.ToLower(info.Name), "field generator") {
+			role.Kind = KindMicroJumpFieldGenerator
+		}
+		role.HullSize = hullSizeFromName(info.Name)
+	case GroupWarpScrambler:
+		// Heavy variants can only be fitted to heavy interdiction cruisers.
+		shipGroup, _ := info.Attribute(AttributeCanFitShipGroup01)
+		heavy := int(shipGroup) == GroupHeavyInterdictionCruiser
+		switch {
+		case role.Range > 0 && role.Range < kWarpScramblerMaxRange && heavy:
+			role.Kind = KindHeavyWarpScrambler
+		case role.Range > 0 && role.Range < kWarpScramblerMaxRange:
+			role.Kind = KindWarpScrambler
+		case heavy:
+			role.Kind = KindHeavyWarpDisruptor
+		default:
+			role.Kind = KindWarpDisruptor
+		}
+	case GroupWeaponDisruptor:
+		// Guidance disruptors are the weapon disruptors that affect missiles.
+		if _, ok := info.Attribute(AttributeMissileVelocityBonus); ok {
+			role.Kind = KindGuidanceDisruptor
+		} else {
+			role.Kind = KindTrackingDisruptor
+		}
+	default:
+		role.Kind = groupKinds[info.GroupID]
+	}
+
+	return role
+}
+
+// propulsionSize derives the nominal size of a propulsion module from the mass it adds,
+// which is the same for the afterburner and microwarpdrive of one hull class.
+func propulsionSize(kind ModuleKind, massAddition float64) int {
+	sizes := []struct {
+		mass        float64
+		afterburner int
+		mwd         int
+	}{
+		{500000, 1, 5},
+		{5000000, 10, 50},
+		{50000000, 100, 500},
+		{500000000, 10000, 50000},
+	}
+
+	if massAddition <= 0 {
+		return 0
+	}
+	for _, size := range sizes {
+		if massAddition <= size.mass {
+			if kind == KindMicrowarpdrive {
+				return size.mwd
+			}
+			return size.afterburner
+		}
+	}
+	return 0
+}
+
+// Hull classes propulsion modules are sized for.
+const (
+	HullSmall   = "Small"
+	HullMedium  = "Medium"
+	HullLarge   = "Large"
+	HullCapital = "Capital"
+)
+
+// hullSizeForMN returns the hull class of an afterburner or microwarpdrive size.
+func hullSizeForMN(sizeMN int) string {
+	switch sizeMN {
+	case 1, 5:
+		return HullSmall
+	case 10, 50:
+		return HullMedium
+	case 100, 500:
+		return HullLarge
+	case 10000, 50000:
+		return HullCapital
+	}
+	return ""
+}
+
+// hullSizeFromName reads the hull class from names such as "Large Micro Jump Drive".
+func hullSizeFromName(name string) string {
+	for _, size := range []string{HullSmall, HullMedium, HullLarge, HullCapital} {
+		if strings.Contains(strings.ToLower(name), strings.ToLower(size)) {
+			return size
+		}
+	}
+	return ""
+}
+
+var propulsionNamePattern = regexp.MustCompile(`\b(\d+)mn\b`)
+
+// classifyModuleByName guesses the role of a type from its English name.
+func classifyModuleByName(typeID int, name string) ModuleRole {
+	role := ModuleRole{TypeID: typeID, Name: name}
+	item := strings.ToLower(name)
+
+	switch {
+	case strings.Contains(item, "micro jump field generator"):
+		role.Kind = KindMicroJumpFieldGenerator
+		role.HullSize = hullSizeFromName(name)
+	case strings.Contains(item, "micro jump drive"):
+		role.Kind = KindMicroJumpDrive
+		role.HullSize = hullSizeFromName(name)
+	case strings.Contains(item, "afterburner") || strings.Contains(item, "microwarpdrive"):
+		role.Kind = KindAfterburner
+		if strings.Contains(item, "microwarpdrive") {
+			role.Kind = KindMicrowarpdrive
+		}
+		if match := propulsionNamePattern.FindStringSubmatch(item); match != nil {
+			role.SizeMN, _ = strconv.Atoi(match[1])
+		}
+		role.HullSize = hullSizeForMN(role.SizeMN)
+	case strings.Contains(item, "heavy warp scrambler"):
+		role.Kind = KindHeavyWarpScrambler
+	case strings.Contains(item, "heavy warp disruptor"):
+		role.Kind = KindHeavyWarpDisruptor
+	case strings.Contains(item, "warp scrambler"):
+		role.Kind = KindWarpScrambler
+	case strings.Contains(item, "warp disruptor"):
+		role.Kind = KindWarpDisruptor
+	case strings.Contains(item, "stasis web"):
+		role.Kind = KindStasisWeb
+	case strings.Contains(item, "stasis grappler"):
+		role.Kind = KindStasisGrappler
+	case strings.Contains(item, "energy neutralizer"):
+		role.Kind = KindEnergyNeutralizer
+	case strings.Contains(item, "nosferatu"):
+		role.Kind = KindEnergyNosferatu
+	case strings.Contains(item, "sensor dampener"):
+		role.Kind = KindSensorDampener
+	case strings.HasPrefix(item, "ecm ") || strings.Contains(item, " ecm "):
+		role.Kind = KindECM
+	case strings.Contains(item, "tracking disruptor"):
+		role.Kind = KindTrackingDisruptor
+	case strings.Contains(item, "guidance disruptor"):
+		role.Kind = KindGuidanceDisruptor
+	case strings.Contains(item, "target painter"):
+		role.Kind = KindTargetPainter
+	case strings.Contains(item, "warp disruption field generator"):
+		role.Kind = KindWarpDisruptFieldGenerator
+	case strings.Contains(item, "interdiction sphere launcher"):
+		role.Kind = KindInterdictionSphereLauncher
+	case strings.Contains(item, "cloaking device"):
+		role.Kind = KindCloak
+	case strings.Contains(item, "cynosural field generator"):
+		role.Kind = KindCynosuralField
+	case strings.Contains(item, "remote armor repairer"):
+		role.Kind = KindRemoteArmorRepairer
+	case strings.Contains(item, "remote shield booster"):
+		role.Kind = KindRemoteShieldBooster
+	case strings.Contains(item, "ancillary shield booster"):
+		role.Kind = KindAncillaryShieldBooster
+	case strings.Contains(item, "ancillary armor repairer"):
+		role.Kind = KindAncillaryArmorRepairer
+	case strings.Contains(item, "warp core stabilizer"):
+		role.Kind = KindWarpCoreStabilizer
+	}
+
+	return role
+}
@@ -0,0 +1,106 @@
+package main
+
+import "testing"
+
+// testStaticData is a small SDE holding a few variants of the modules the classifier knows.
+func testStaticData() *StaticData {
+	data := &StaticData{
+		Types: map[int]sdeType{
+			447:   {Name: "Warp Scrambler I", GroupID: GroupWarpScrambler},
+			28514: {Name: "Domination Warp Scrambler", GroupID: GroupWarpScrambler},
+			3242:  {Name: "Warp Disruptor I", GroupID: GroupWarpScrambler},
+			14268: {Name: "True Sansha Stasis Webifier", GroupID: GroupStasisWeb},
+			440:   {Name: "5MN Microwarpdrive II", GroupID: GroupPropulsionModule},
+			12076: {Name: "50MN Microwarpdrive II", GroupID: GroupPropulsionModule},
+			12058: {Name: "10MN Afterburner II", GroupID: GroupPropulsionModule},
+			41236: {Name: "10000MN Y-S8 Compact Afterburner", GroupID: GroupPropulsionModule},
+			2048:  {Name: "Damage Control II", GroupID: 60},
+			1968:  {Name: "Remote Sensor Dampener II", GroupID: GroupSensorDampener},
+		},
+		Groups: map[int]sdeGroup{
+			GroupWarpScrambler:    {Name: "Warp Scrambler", CategoryID: 7},
+			GroupStasisWeb:        {Name: "Stasis Web", CategoryID: 7},
+			GroupPropulsionModule: {Name: "Propulsion Module", CategoryID: 7},
+			60:                    {Name: "Damage Control", CategoryID: 7},
+			GroupSensorDampener:   {Name: "Sensor Dampener", CategoryID: 7},
+		},
+		Attributes: map[int]map[int]float64{
+			447:   {AttributeMaxRange: 7500, AttributeWarpScrambleStrength: 2},
+			28514: {AttributeMaxRange: 9000, AttributeWarpScrambleStrength: 2, AttributeMetaGroup: 4, AttributeMetaLevel: 8},
+			3242:  {AttributeMaxRange: 20000, AttributeWarpScrambleStrength: 1},
+			14268: {AttributeMaxRange: 14000, AttributeSpeedFactor: -60, AttributeMetaGroup: 4},
+			440:   {AttributeMassAddition: 500000, AttributeSignatureRadiusBonus: 500},
+			12076: {AttributeMassAddition: 5000000, AttributeSignatureRadiusBonus: 500},
+			12058: {AttributeMassAddition: 5000000},
+			41236: {AttributeMassAddition: 500000000},
+			1968:  {AttributeMaxRange: 30000, AttributeMaxTargetRangeBonus: -30, AttributeScanResolutionBonus: -30},
+		},
+	}
+	return data
+}
+
+func TestClassifyModule(t *testing.T) {
+	previous := staticData
+	SetStaticData(testStaticData())
+	defer SetStaticData(previous)
+
+	tests := []struct {
+		typeID int
+		kind   ModuleKind
+		sizeMN int
+	}{
+		{447, KindWarpScrambler, 0},
+		{28514, KindWarpScrambler, 0},
+		{3242, KindWarpDisruptor, 0},
+		{14268, KindStasisWeb, 0},
+		{440, KindMicrowarpdrive, 5},
+		{12076, KindMicrowarpdrive, 50},
+		{12058, KindAfterburner, 10},
+		{41236, KindAfterburner, 10000},
+		{2048, KindOther, 0},
+		{1968, KindSensorDampener, 0},
+	}
+	for _, test := range tests {
+		// The name is deliberately misleading, the SDE must take precedence.
+		role := ClassifyModule(test.typeID, "Stasis Webifier")
+		if role.Kind != test.kind || role.SizeMN != test.sizeMN || !role.FromSDE {
+			t.Errorf("Expected type %d to be a %v of %vMN, got: %+v", test.typeID, test.kind, test.sizeMN, role)
+		}
+	}
+
+	role := ClassifyModule(28514, "")
+	if role.MetaGroupID != 4 || role.MetaLevel != 8 || role.ScrambleStrength != 2 {
+		t.Errorf("Unexpected faction scrambler role: %+v", role)
+	}
+
+	role = ClassifyModule(1968, "")
+	if role.DampTargetRange != -30 || role.DampScanRes != -30 {
+		t.Errorf("Unexpected sensor dampener role: %+v", role)
+	}
+}
+
+func TestClassifyModuleByName(t *testing.T) {
+	previous := staticData
+	SetStaticData(nil)
+	defer SetStaticData(previous)
+
+	tests := []struct {
+		name   string
+		kind   ModuleKind
+		sizeMN int
+	}{
+		{"Federation Navy Warp Disruptor", KindWarpDisruptor, 0},
+		{"500MN Quad LiF Restrained Microwarpdrive", KindMicrowarpdrive, 500},
+		{"1MN Afterburner II", KindAfterburner, 1},
+		{"10000MN Y-S8 Compact Afterburner", KindAfterburner, 10000},
+		{"Multispectrum ECM II", KindECM, 0},
+		{"Medium Micro Jump Drive", KindMicroJumpDrive, 0},
+		{"Damage Control II", KindOther, 0},
+	}
+	for _, test := range tests {
+		role := ClassifyModule(1, test.name)
+		if role.Kind != test.kind || role.SizeMN != test.sizeMN || role.FromSDE {
+			t.Errorf("Expected %q to be a %v of %vMN, got: %+v", test.name, test.kind, test.sizeMN, role)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// RulesFile is the JSON file defining extra loss table columns, for example:
+//
+//	{"columns": [
+//		{"id": "cyno", "header": "Cyno", "kinds": ["Cynosural Field Generator"]},
+//		{"id": "mjd", "header": "MJD", "names": ["micro jump drive"], "aggregate": "max_size"},
+//		{"id": "links", "header": "Links", "group_ids": [1770], "aggregate": "count"}
+//	]}
+//
+// Its columns are added after the built-in ones, a column with the ID of a built-in one replaces it.
+type RulesFile struct {
+	Columns []LossColumn `json:"columns"`
+}
+
+// DefaultRulesPath returns the location of the rules file inside the user config directory.
+func DefaultRulesPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "go-eye", "rules.json"), nil
+}
+
+// LoadRules reads the rules file at path and returns the columns it defines.
+func LoadRules(path string) ([]LossColumn, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open rules: %w", err)
+	}
+	defer file.Close()
+
+	var rules RulesFile
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&rules); err != nil {
+		return nil, fmt.Errorf("failed to read rules: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	for i := range rules.Columns {
+		if err := rules.Columns[i].compile(); err != nil {
+			return nil, fmt.Errorf("invalid rules: %w", err)
+		}
+		if seen[rules.Columns[i].ID] {
+			return nil, fmt.Errorf("invalid rules: column %q is defined twice", rules.Columns[i].ID)
+		}
+		seen[rules.Columns[i].ID] = true
+	}
+
+	return rules.Columns, nil
+}
+
+// mergeColumns lays the rule columns over the built-in ones.
+func mergeColumns(builtin []LossColumn, rules []LossColumn) []LossColumn {
+	merged := make([]LossColumn, len(builtin))
+	copy(merged, builtin)
+
+	for _, rule := range rules {
+		replaced := false
+		for i := range merged {
+			if merged[i].ID == rule.ID {
+				merged[i] = rule
+				replaced = true
+			}
+		}
+		if !replaced {
+			merged = append(merged, rule)
+		}
+	}
+	return merged
+}
+
+// ApplyRules loads the rules file at path and makes its columns available in the loss table.
+// Without a rules file, only the built-in columns are available.
+func ApplyRules(path string) error {
+	rules, err := LoadRules(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	SetLossColumns(mergeColumns(mustCompileColumns(builtinLossColumns), rules))
+	return nil
+}
+
+// rulesFileState identifies a version of the rules file, the zero value standing for no file.
+type rulesFileState struct {
+	modTime time.Time
+	size    int64
+}
+
+func statRulesFile(path string) rulesFileState {
+	info, err := os.Stat(path)
+	if err != nil {
+		return rulesFileState{}
+	}
+	return rulesFileState{modTime: info.ModTime(), size: info.Size()}
+}
+
+// WatchRules applies the rules file at path again whenever it changes, checking every interval,
+// and calls onChange once the new columns are in place. An invalid rules file is reported and
+// the columns in place are kept. The rules are expected to be applied already when it starts.
+func WatchRules(path string, interval time.Duration, onChange func()) {
+	last := statRulesFile(path)
+	for {
+		time.Sleep(interval)
+
+		current := statRulesFile(path)
+		if current == last {
+			continue
+		}
+		last = current
+
+		if err := ApplyRules(path); err != nil {
+			fmt.Printf("Error occurred: %v\n", err)
+			continue
+		}
+		onChange()
+	}
+}
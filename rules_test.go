@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestApplyRules(t *testing.T) {
+	previous := LossColumns()
+	defer SetLossColumns(previous)
+
+	path := filepath.Join(t.TempDir(), "rules.json")
+	err := os.WriteFile(path, []byte(`{"columns": [
+		{"id": "web", "header": "Webs", "kinds": ["stasis webifier"], "aggregate": "presence"},
+		{"id": "links", "header": "Links", "group_ids": [1770], "aggregate": "count"},
+		{"id": "dcu", "header": "DCU", "type_ids": [2048]},
+		{"id": "mjd", "header": "MJD", "names": ["micro jump drive$"], "aggregate": "max_size"}
+	]}`), 0o644)
+	if err != nil {
+		t.Fatalf("Error occurred: %v", err)
+	}
+
+	if err := ApplyRules(path); err != nil {
+		t.Errorf("Error occurred: %v", err)
+		return
+	}
+
+	columns := LossColumns()
+	if len(columns) != len(builtinLossColumns)+3 {
+		t.Errorf("Expected the rules to replace one column and add three, got: %d columns", len(columns))
+	}
+
+	roles := []ModuleRole{
+		{TypeID: 2048, Name: "Damage Control II", GroupID: 60},
+		{TypeID: 1, Name: "Shield Command Burst II", GroupID: 1770},
+		{TypeID: 2, Name: "Shield Command Burst I", GroupID: 1770},
+		{TypeID: 3, Name: "Large Micro Jump Drive", Kind: KindMicroJumpDrive, HullSize: HullLarge},
+		{TypeID: 4, Name: "Stasis Webifier II", Kind: KindStasisWeb},
+		{TypeID: 5, Name: "Stasis Webifier II", Kind: KindStasisWeb},
+	}
+	values := lossColumnValues(roles)
+	expected := map[string]string{"web": "O", "links": "2", "dcu": "O", "mjd": "Large", "scram": "X"}
+	for id, value := range expected {
+		if values[id] != value {
+			t.Errorf("Expected %q in column %v, got: %q", value, id, values[id])
+		}
+	}
+
+	// Removing the rules file brings the built-in columns back.
+	os.Remove(path)
+	if err := ApplyRules(path); err != nil {
+		t.Errorf("Error occurred: %v", err)
+	}
+	if len(LossColumns()) != len(builtinLossColumns) {
+		t.Errorf("Expected only the built-in columns, got: %d columns", len(LossColumns()))
+	}
+}
+
+func TestLoadRulesInvalid(t *testing.T) {
+	tests := map[string]string{
+		"aggregate": `{"columns": [{"id": "a", "header": "A", "aggregate": "sum"}]}`,
+		"kind":      `{"columns": [{"id": "a", "header": "A", "kinds": ["Doomsday"]}]}`,
+		"pattern":   `{"columns": [{"id": "a", "header": "A", "names": ["("]}]}`,
+		"duplicate": `{"columns": [{"id": "a", "header": "A"}, {"id": "a", "header": "B"}]}`,
+		"header":    `{"columns": [{"id": "a"}]}`,
+		"field":     `{"columns": [{"id": "a", "header": "A", "typeids": [1]}]}`,
+	}
+	for name, rules := range tests {
+		path := filepath.Join(t.TempDir(), "rules.json")
+		if err := os.WriteFile(path, []byte(rules), 0o644); err != nil {
+			t.Fatalf("Error occurred: %v", err)
+		}
+		if _, err := LoadRules(path); err == nil {
+			t.Errorf("Expected the %v rules to be rejected", name)
+		}
+	}
+}
+
+func TestLossColumnChoices(t *testing.T) {
+	defer SetLossColumnChoices("")
+
+	SetLossColumnChoices("cyno=1,damp=0,gone=1")
+	ids := strings.Join(VisibleLossColumnIDs(), ",")
+	if ids != "prop,scram,point,web,neut,cyno" {
+		t.Errorf("Unexpected visible columns: %v", ids)
+	}
+	if saved := LossColumnChoices(); saved != "damp=0,cyno=1,gone=1" {
+		t.Errorf("Unexpected saved choices: %v", saved)
+	}
+}
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SDE category IDs used by the analysis.
+const (
+	SDECategoryModule = 7
+	SDECategoryCharge = 8
+)
+
+// Dogma attribute IDs used by the analysis.
+const (
+	AttributeMetaLevel = 633
+	AttributeMetaGroup = 1692
+)
+
+// TypeInfo describes an inventory type as found in the Static Data Export.
+type TypeInfo struct {
+	TypeID          int
+	Name            string
+	GroupID         int
+	GroupName       string
+	CategoryID      int
+	CategoryName    string
+	MarketGroupID   int
+	MarketGroupName string
+	Published       bool
+	// Attributes maps dogma attribute IDs to their values.
+	Attributes map[int]float64
+}
+
+// Attribute returns the value of a dogma attribute, and whether the type has it.
+func (t TypeInfo) Attribute(attributeID int) (float64, bool) {
+	value, ok := t.Attributes[attributeID]
+	return value, ok
+}
+
+// MetaLevel returns the meta level of the type, zero when it has none.
+func (t TypeInfo) MetaLevel() int {
+	value, _ := t.Attribute(AttributeMetaLevel)
+	return int(value)
+}
+
+// sdeType is a row of invTypes.
+type sdeType struct {
+	Name          string
+	GroupID       int
+	MarketGroupID int
+	Published     bool
+}
+
+// sdeGroup is a row of invGroups.
+type sdeGroup struct {
+	Name       string
+	CategoryID int
+}
+
+// sdeMarketGroup is a row of invMarketGroups.
+type sdeMarketGroup struct {
+	Name     string
+	ParentID int
+}
+
+// StaticData is the subset of the Static Data Export go-eye needs, keyed by ID.
+// It is imported once from the SDE CSV files and then kept as a single gob file.
+type StaticData struct {
+	Imported     time.Time
+	Types        map[int]sdeType
+	Groups       map[int]sdeGroup
+	Categories   map[int]string
+	MarketGroups map[int]sdeMarketGroup
+	Attributes   map[int]map[int]float64
+}
+
+// The CSV files ImportStaticData reads, as published by the Fuzzwork SDE conversion.
+const (
+	sdeTypesFile        = "invTypes.csv"
+	sdeGroupsFile       = "invGroups.csv"
+	sdeCategoriesFile   = "invCategories.csv"
+	sdeMarketGroupsFile = "invMarketGroups.csv"
+	sdeAttributesFile   = "dgmTypeAttributes.csv"
+)
+
+// DefaultStaticDataPath returns the location of the imported SDE inside the user config directory.
+func DefaultStaticDataPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "go-eye", "sde.gob"), nil
+}
+
+// DefaultStaticDataImportDir returns the directory the SDE CSV files are imported from.
+func DefaultStaticDataImportDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "go-eye", "sde"), nil
+}
+
+// ImportStaticData reads the SDE CSV files in dir. The dogma attributes file is optional.
+func ImportStaticData(dir string) (*StaticData, error) {
+	data := &StaticData{
+		Imported:     time.Now(),
+		Types:        make(map[int]sdeType),
+		Groups:       make(map[int]sdeGroup),
+		Categories:   make(map[int]string),
+		MarketGroups: make(map[int]sdeMarketGroup),
+		Attributes:   make(map[int]map[int]float64),
+	}
+
+	err := readSDEFile(filepath.Join(dir, sdeTypesFile), func(row sdeRow) error {
+		data.Types[row.int("typeID")] = sdeType{
+			Name:          row.str("typeName"),
+			GroupID:       row.int("groupID"),
+			MarketGroupID: row.int("marketGroupID"),
+			Published:     row.int("published") == 1,
+		}
+		return row.err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = readSDEFile(filepath.Join(dir, sdeGroupsFile), func(row sdeRow) error {
+		data.Groups[row.int("groupID")] = sdeGroup{
+			Name:       row.str("groupName"),
+			CategoryID: row.int("categoryID"),
+		}
+		return row.err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = readSDEFile(filepath.Join(dir, sdeCategoriesFile), func(row sdeRow) error {
+		data.Categories[row.int("categoryID")] = row.str("categoryName")
+		return row.err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = readSDEFile(filepath.Join(dir, sdeMarketGroupsFile), func(row sdeRow) error {
+		data.MarketGroups[row.int("marketGroupID")] = sdeMarketGroup{
+			Name:     row.str("marketGroupName"),
+			ParentID: row.int("parentGroupID"),
+		}
+		return row.err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = readSDEFile(filepath.Join(dir, sdeAttributesFile), func(row sdeRow) error {
+		typeID := row.int("typeID")
+		// Fuzzwork fills one of valueInt and valueFloat, leaving the other as None.
+		value := row.float("valueFloat")
+		if row.str("valueFloat") == "" {
+			value = float64(row.int("valueInt"))
+		}
+		if data.Attributes[typeID] == nil {
+			data.Attributes[typeID] = make(map[int]float64)
+		}
+		data.Attributes[typeID][row.int("attributeID")] = value
+		return row.err
+	})
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// sdeRow gives access to a CSV record by column name, keeping the first parse error.
+type sdeRow struct {
+	columns map[string]int
+	record  []string
+	err     error
+}
+
+// str returns a column's text, with the SDE's "None" read as empty.
+func (r *sdeRow) str(column string) string {
+	i, ok := r.columns[column]
+	if !ok || i >= len(r.record) || r.record[i] == "None" {
+		return ""
+	}
+	return r.record[i]
+}
+
+func (r *sdeRow) int(column string) int {
+	text := r.str(column)
+	if text == "" {
+		return 0
+	}
+	value, err := strconv.Atoi(text)
+	if err != nil && r.err == nil {
+		r.err = fmt.Errorf("invalid %s %q: %w", column, text, err)
+	}
+	return value
+}
+
+func (r *sdeRow) float(column string) float64 {
+	text := r.str(column)
+	if text == "" {
+		return 0
+	}
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil && r.err == nil {
+		r.err = fmt.Errorf("invalid %s %q: %w", column, text, err)
+	}
+	return value
+}
+
+// readSDEFile calls fn for every record of a CSV file whose first line names the columns.
+func readSDEFile(path string, fn func(row sdeRow) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open SDE file: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[name] = i
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+		}
+
+		if err := fn(sdeRow{columns: columns, record: record}); err != nil {
+			return fmt.Errorf("failed to import %s: %w", filepath.Base(path), err)
+		}
+	}
+}
+
+// LoadStaticData reads SDE data previously written by Save.
+func LoadStaticData(path string) (*StaticData, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open static data: %w", err)
+	}
+	defer file.Close()
+
+	var data StaticData
+	if err := gob.NewDecoder(file).Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to read static data: %w", err)
+	}
+	return &data, nil
+}
+
+// Save writes the SDE data to path, replacing it atomically.
+func (d *StaticData) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create static data directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "sde-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write static data: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := gob.NewEncoder(tmp).Encode(d); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write static data: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write static data: %w", err)
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// TypeInfo looks up a type by ID, and reports whether the SDE knows it.
+func (d *StaticData) TypeInfo(typeID int) (TypeInfo, bool) {
+	typ, ok := d.Types[typeID]
+	if !ok {
+		return TypeInfo{}, false
+	}
+
+	group := d.Groups[typ.GroupID]
+	return TypeInfo{
+		TypeID:          typeID,
+		Name:            typ.Name,
+		GroupID:         typ.GroupID,
+		GroupName:       group.Name,
+		CategoryID:      group.CategoryID,
+		CategoryName:    d.Categories[group.CategoryID],
+		MarketGroupID:   typ.MarketGroupID,
+		MarketGroupName: d.MarketGroups[typ.MarketGroupID].Name,
+		Published:       typ.Published,
+		Attributes:      d.Attributes[typeID],
+	}, true
+}
+
+// InMarketGroup reports whether a type is listed under marketGroupID or one of its subgroups.
+func (d *StaticData) InMarketGroup(typeID int, marketGroupID int) bool {
+	current := d.Types[typeID].MarketGroupID
+	for depth := 0; current != 0 && depth < 16; depth++ {
+		if current == marketGroupID {
+			return true
+		}
+		current = d.MarketGroups[current].ParentID
+	}
+	return false
+}
+
+// staticData is the SDE used by the package level lookups, nil until one is loaded.
+var (
+	staticData   *StaticData
+	staticDataMu sync.RWMutex
+)
+
+// SetStaticData replaces the SDE used by the package level lookups.
+func SetStaticData(data *StaticData) {
+	staticDataMu.Lock()
+	defer staticDataMu.Unlock()
+	staticData = data
+}
+
+// LookupType looks up a type in the loaded SDE. It reports false when no SDE is loaded
+// or the type is unknown, callers then fall back to the type name.
+func LookupType(typeID int) (TypeInfo, bool) {
+	staticDataMu.RLock()
+	data := staticData
+	staticDataMu.RUnlock()
+
+	if data == nil {
+		return TypeInfo{}, false
+	}
+	return data.TypeInfo(typeID)
+}
+
+// OpenStaticData loads the imported SDE at path. When there is none yet, or the CSV files
+// in importDir are newer, they are imported and saved to path for the next start.
+// It returns nil without error when there is neither.
+func OpenStaticData(path string, importDir string) (*StaticData, error) {
+	data, err := LoadStaticData(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	csvInfo, statErr := os.Stat(filepath.Join(importDir, sdeTypesFile))
+	if data != nil && (statErr != nil || !csvInfo.ModTime().After(data.Imported)) {
+		return data, nil
+	} else if data == nil && errors.Is(statErr, os.ErrNotExist) {
+		// Nothing imported yet, analysis falls back to type names.
+		return nil, nil
+	}
+
+	data, err = ImportStaticData(importDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := data.Save(path); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
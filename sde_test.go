@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestSDE writes a tiny SDE in the Fuzzwork CSV layout and returns its directory.
+func writeTestSDE(t *testing.T) string {
+	dir := t.TempDir()
+	files := map[string]string{
+		sdeTypesFile: "typeID,groupID,typeName,description,mass,volume,capacity,portionSize,raceID,basePrice,published,marketGroupID,iconID,soundID,graphicID\n" +
+			"447,52,Warp Scrambler I,\"Disrupts, and scrambles\",0,5,0,1,None,0,1,1936,None,None,None\n" +
+			"28514,52,Domination Warp Scrambler,None,0,5,0,1,None,0,1,1937,None,None,None\n",
+		sdeGroupsFile: "groupID,categoryID,groupName,iconID,useBasePrice,anchored,anchorable,fittableNonSingleton,published\n" +
+			"52,7,Warp Scrambler,None,0,0,0,0,1\n",
+		sdeCategoriesFile: "categoryID,categoryName,iconID,published\n" +
+			"7,Module,None,1\n",
+		sdeMarketGroupsFile: "marketGroupID,parentGroupID,marketGroupName,description,iconID,hasTypes\n" +
+			"1935,None,Warp Scramblers,None,None,0\n" +
+			"1936,1935,Tech I,None,None,1\n" +
+			"1937,1935,Faction,None,None,1\n",
+		sdeAttributesFile: "typeID,attributeID,valueInt,valueFloat\n" +
+			"447,633,0,None\n" +
+			"28514,633,None,8.0\n" +
+			"28514,20,None,-0.25\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("Error occurred: %v", err)
+		}
+	}
+	return dir
+}
+
+func TestImportStaticData(t *testing.T) {
+	data, err := ImportStaticData(writeTestSDE(t))
+	if err != nil {
+		t.Errorf("Error occurred: %v", err)
+		return
+	}
+
+	info, ok := data.TypeInfo(28514)
+	if !ok {
+		t.Errorf("Expected type 28514 to be known")
+		return
+	}
+	if info.Name != "Domination Warp Scrambler" || info.GroupName != "Warp Scrambler" || info.CategoryName != "Module" || info.MarketGroupName != "Faction" {
+		t.Errorf("Unexpected type info: %+v", info)
+	}
+	if info.MetaLevel() != 8 {
+		t.Errorf("Expected meta level 8, got %v", info.MetaLevel())
+	}
+	if value, _ := info.Attribute(20); value != -0.25 {
+		t.Errorf("Expected attribute 20 to be -0.25, got %v", value)
+	}
+
+	if !data.InMarketGroup(447, 1935) || data.InMarketGroup(447, 1937) {
+		t.Errorf("Unexpected market group membership for type 447")
+	}
+	if _, ok := data.TypeInfo(1); ok {
+		t.Errorf("Expected unknown type to be missing")
+	}
+}
+
+func TestOpenStaticData(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sde.gob")
+
+	data, err := OpenStaticData(path, filepath.Join(t.TempDir(), "missing"))
+	if err != nil || data != nil {
+		t.Errorf("Expected no static data without an import, got %v, %v", data, err)
+	}
+
+	_, err = OpenStaticData(path, writeTestSDE(t))
+	if err != nil {
+		t.Errorf("Error occurred: %v", err)
+		return
+	}
+
+	loaded, err := LoadStaticData(path)
+	if err != nil {
+		t.Errorf("Error occurred: %v", err)
+		return
+	}
+
+	previous := staticData
+	SetStaticData(loaded)
+	defer SetStaticData(previous)
+
+	info, ok := LookupType(447)
+	if !ok || info.GroupID != 52 || info.MetaLevel() != 0 {
+		t.Errorf("Unexpected type info: %+v", info)
+	}
+}
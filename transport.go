@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// newTransport builds the HTTP transport shared by the ESI and zKillboard clients. Connections
+// are kept alive and reused, HTTP/2 is negotiated when the server offers it, and gzip responses
+// are requested and decompressed transparently. Requests go through proxyURL when set, otherwise
+// through the proxy named by the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+func newTransport(proxyURL string) (*http.Transport, error) {
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   KHTTPDialTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   KESIBatchConcurrency + KKillmailWorkers,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   KHTTPDialTimeout,
+		ResponseHeaderTimeout: KHTTPTimeout,
+		ExpectContinueTimeout: time.Second,
+	}
+
+	if proxyURL != "" {
+		proxy, err := url.Parse(proxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL %q: %w", proxyURL, err)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	return transport, nil
+}
+
+// sharedHTTPClient is the HTTP client used by the default ESI and zKillboard clients.
+var sharedHTTPClient = newSharedHTTPClient()
+
+func newSharedHTTPClient() *http.Client {
+	transport, err := newTransport(KHTTPProxy)
+	if err != nil {
+		fmt.Printf("Error occurred: %v\n", err)
+		transport, _ = newTransport("")
+	}
+	return &http.Client{Transport: transport, Timeout: KHTTPTimeout}
+}
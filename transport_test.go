@@ -0,0 +1,28 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestNewTransportUsesConfiguredProxy(t *testing.T) {
+	transport, err := newTransport("http://127.0.0.1:3128")
+	if err != nil {
+		t.Errorf("Error occurred: %v", err)
+		return
+	}
+
+	req, _ := http.NewRequest("GET", "https://esi.evetech.net/latest/status/", nil)
+	proxy, err := transport.Proxy(req)
+	if err != nil || proxy == nil || proxy.Host != "127.0.0.1:3128" {
+		t.Errorf("Expected requests to go through the proxy, got: %v, %v", proxy, err)
+	}
+
+	if !transport.ForceAttemptHTTP2 || transport.DisableCompression || transport.DisableKeepAlives {
+		t.Errorf("Unexpected transport settings: %+v", transport)
+	}
+
+	if _, err := newTransport("://bad"); err == nil {
+		t.Errorf("Expected an invalid proxy URL to be rejected")
+	}
+}
@@ -1,14 +1,57 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"strings"
 )
 
-type Killmail struct {
+// ZKillClient holds the settings used to talk to the zKillboard API.
+type ZKillClient struct {
+	BaseURL    string
+	UserAgent  string
+	HTTPClient *http.Client
+	Limiter    *RateLimiter
+	Retry      RetryPolicy
+}
+
+// NewZKillClient creates a ZKillClient pointed at the live zKillboard site.
+func NewZKillClient() *ZKillClient {
+	return &ZKillClient{
+		BaseURL:    KZKillBaseURL,
+		UserAgent:  KUserAgent,
+		HTTPClient: sharedHTTPClient,
+		Limiter:    zkillRateLimiter,
+		Retry:      DefaultRetryPolicy,
+	}
+}
+
+// zkillClient is the client used by the package level zKillboard functions.
+var zkillClient = NewZKillClient()
+
+// SetZKillClient replaces the client used by the package level zKillboard functions.
+func SetZKillClient(client *ZKillClient) {
+	zkillClient = client
+}
+
+// get fetches a zKillboard API route with the common headers set, retrying on throttling.
+func (c *ZKillClient) get(ctx context.Context, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", strings.TrimRight(c.BaseURL, "/")+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("accept", "application/json")
+	req.Header.Add("User-Agent", c.UserAgent)
+
+	return doWithRetry(c.HTTPClient, c.Limiter, c.Retry, req)
+}
+
+// ZKillmail is a killmail reference as listed by zKillboard, carrying the hash needed to fetch it from ESI.
+type ZKillmail struct {
 	KillmailID int `json:"killmail_id"`
 	ZKB        struct {
 		Hash string `json:"hash"`
@@ -27,42 +70,41 @@ type KillmailStats struct {
 	} `json:"topAllTime"`
 }
 
-var killmailCache = make(map[string][]Killmail)
-var killmailCached = make(map[string]bool)
-
-var killmailStatsCache = make(map[string]KillmailStats)
+func GetRecentLosses(ctx context.Context, characterID int, shipID int) ([]ZKillmail, error) {
+	key := fmt.Sprintf("losses:%d_%d", characterID, shipID)
 
-func GetRecentLosses(characterID int, shipID int) ([]Killmail, error) {
-	if killmailCached[fmt.Sprintf("%d_%d", characterID, shipID)] {
-		return getRecentLossesFromCache(characterID, shipID), nil
+	var cached []ZKillmail
+	if getCachedJSON(sharedCache, key, &cached) {
+		return mostRecent(cached), nil
 	}
 
-	url := fmt.Sprintf("https://zkillboard.com/api/losses/characterID/%d/shipTypeID/%d/", characterID, shipID)
-	killmails, err := fetchRecentLossesFromAPI(url)
+	killmails, err := doShared(ctx, requests, key, func(ctx context.Context) ([]ZKillmail, error) {
+		path := fmt.Sprintf("/losses/characterID/%d/shipTypeID/%d/", characterID, shipID)
+		killmails, err := zkillClient.fetchRecentLossesFromAPI(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+
+		setCachedJSON(sharedCache, key, killmails, KCacheTTLZKill)
+		return killmails, nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	key := fmt.Sprintf("%d_%d", characterID, shipID)
-	killmailCache[key] = killmails
-	killmailCached[key] = true
-
-	if len(killmails) > 8 {
-		return killmails[:8], nil
-	} else {
-		return killmails, nil
-	}
+	return mostRecent(killmails), nil
 }
 
-func getRecentLossesFromCache(characterID int, shipID int) []Killmail {
-	if killmailCached[fmt.Sprintf("%d_%d", characterID, shipID)] {
-		return killmailCache[fmt.Sprintf("%d_%d", characterID, shipID)]
+// mostRecent keeps the first eight killmails, zKillboard lists the newest first.
+func mostRecent(killmails []ZKillmail) []ZKillmail {
+	if len(killmails) > 8 {
+		return killmails[:8]
 	}
-	return nil
+	return killmails
 }
 
-func fetchRecentLossesFromAPI(url string) ([]Killmail, error) {
-	resp, err := http.Get(url)
+func (c *ZKillClient) fetchRecentLossesFromAPI(ctx context.Context, path string) ([]ZKillmail, error) {
+	resp, err := c.get(ctx, path)
 	if err != nil {
 		return nil, err
 	}
@@ -73,7 +115,11 @@ func fetchRecentLossesFromAPI(url string) ([]Killmail, error) {
 		}
 	}(resp.Body)
 
-	var killmails []Killmail
+	if err := checkResponse("zKillboard", resp); err != nil {
+		return nil, err
+	}
+
+	var killmails []ZKillmail
 	err = json.NewDecoder(resp.Body).Decode(&killmails)
 	if err != nil {
 		return nil, err
@@ -82,30 +128,50 @@ func fetchRecentLossesFromAPI(url string) ([]Killmail, error) {
 	return killmails, nil
 }
 
-func GetTopShips(characterID int) ([]int, error) {
-	if killmailStats, ok := killmailStatsCache[fmt.Sprintf("%d", characterID)]; ok {
-		var result []int
-		for _, topShip := range killmailStats.TopAllTime[4].Data {
-			result = append(result, topShip.ShipTypeID)
-		}
-		return result, nil
+func GetTopShips(ctx context.Context, characterID int) ([]int, error) {
+	key := fmt.Sprintf("stats:%d", characterID)
+
+	var cached KillmailStats
+	if getCachedJSON(sharedCache, key, &cached) {
+		return topShipIDs(cached)
 	}
 
-	url := fmt.Sprintf("https://zkillboard.com/api/stats/characterID/%d/", characterID)
-	killmailStats, err := fetchTopShipsFromAPI(url)
+	killmailStats, err := doShared(ctx, requests, key, func(ctx context.Context) (KillmailStats, error) {
+		path := fmt.Sprintf("/stats/characterID/%d/", characterID)
+		killmailStats, err := zkillClient.fetchTopShipsFromAPI(ctx, path)
+		if err != nil {
+			return KillmailStats{}, err
+		}
+
+		setCachedJSON(sharedCache, key, killmailStats, KCacheTTLZKill)
+		return killmailStats, nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	var result []int
-	for _, topShip := range killmailStats.TopAllTime[4].Data {
-		result = append(result, topShip.ShipTypeID)
+	return topShipIDs(killmailStats)
+}
+
+// topShipIDs extracts the all time top ship type IDs from zKillboard stats.
+func topShipIDs(killmailStats KillmailStats) ([]int, error) {
+	for _, top := range killmailStats.TopAllTime {
+		if top.Type != "ship" {
+			continue
+		}
+
+		var result []int
+		for _, topShip := range top.Data {
+			result = append(result, topShip.ShipTypeID)
+		}
+		return result, nil
 	}
-	return result, nil
+
+	return nil, fmt.Errorf("no top ships for character %d: %w", killmailStats.ID, ErrNotFound)
 }
 
-func fetchTopShipsFromAPI(url string) (KillmailStats, error) {
-	resp, err := http.Get(url)
+func (c *ZKillClient) fetchTopShipsFromAPI(ctx context.Context, path string) (KillmailStats, error) {
+	resp, err := c.get(ctx, path)
 	if err != nil {
 		return KillmailStats{}, err
 	}
@@ -116,6 +182,10 @@ func fetchTopShipsFromAPI(url string) (KillmailStats, error) {
 		}
 	}(resp.Body)
 
+	if err := checkResponse("zKillboard", resp); err != nil {
+		return KillmailStats{}, err
+	}
+
 	var killmailStats KillmailStats
 	err = json.NewDecoder(resp.Body).Decode(&killmailStats)
 	if err != nil {
@@ -1,32 +1,102 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
 )
 
+func TestZKillClientBaseURLAndUserAgent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/losses/characterID/1/shipTypeID/587/" {
+			t.Errorf("Unexpected path: %v", r.URL.Path)
+		}
+		if r.Header.Get("User-Agent") != KUserAgent {
+			t.Errorf("Unexpected User-Agent: %v", r.Header.Get("User-Agent"))
+		}
+		fmt.Fprint(w, `[{"killmail_id": 1, "zkb": {"hash": "abc"}}]`)
+	}))
+	defer server.Close()
+
+	client := NewZKillClient()
+	client.BaseURL = server.URL + "/api"
+	client.HTTPClient = server.Client()
+
+	killmails, err := client.fetchRecentLossesFromAPI(context.Background(), "/losses/characterID/1/shipTypeID/587/")
+	if err != nil {
+		t.Errorf("Error occurred: %v", err)
+		return
+	}
+
+	if len(killmails) != 1 || killmails[0].ZKB.Hash != "abc" {
+		t.Errorf("Unexpected killmails: %v", killmails)
+	}
+}
+
+// newFakeZKill starts a stand-in zKillboard server answering with handler and points the lookups at it for the rest of the test.
+func newFakeZKill(t *testing.T, handler http.HandlerFunc) {
+	server := httptest.NewServer(handler)
+
+	client := NewZKillClient()
+	client.BaseURL = server.URL
+	client.HTTPClient = server.Client()
+	client.Limiter = nil
+
+	previousClient, previousCache := zkillClient, sharedCache
+	SetZKillClient(client)
+	SetCache(NewMemoryCache())
+	t.Cleanup(func() {
+		SetZKillClient(previousClient)
+		SetCache(previousCache)
+		server.Close()
+	})
+}
+
 func TestGetRecentLosses(t *testing.T) {
-	characterID := 2117477599
-	shipID := 22430
+	newFakeZKill(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/losses/characterID/2117477599/shipTypeID/22430/" {
+			t.Errorf("Unexpected path: %v", r.URL.Path)
+		}
+		losses := make([]string, 0)
+		for id := 1; id <= 10; id++ {
+			losses = append(losses, fmt.Sprintf(`{"killmail_id": %d, "zkb": {"hash": "hash%d"}}`, id, id))
+		}
+		fmt.Fprintf(w, "[%s]", strings.Join(losses, ","))
+	})
 
-	killmails, err := GetRecentLosses(characterID, shipID)
+	killmails, err := GetRecentLosses(context.Background(), 2117477599, 22430)
 	if err != nil {
 		t.Errorf("Error occurred: %v", err)
 		return
 	}
 
-	if len(killmails) == 2 {
-		t.Logf("Expected killmails, got: %v", killmails)
+	if len(killmails) != 8 || killmails[0].KillmailID != 1 || killmails[7].ZKB.Hash != "hash8" {
+		t.Errorf("Expected the 8 most recent killmails, got: %v", killmails)
 	}
 }
 
 func TestGetTopShips(t *testing.T) {
-	characterID := 2117477599
+	newFakeZKill(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/stats/characterID/2117477599/" {
+			t.Errorf("Unexpected path: %v", r.URL.Path)
+		}
+		fmt.Fprint(w, `{"type": "characterID", "id": 2117477599, "topAllTime": [
+			{"type": "character", "data": [{"kills": 3, "characterID": 1}]},
+			{"type": "ship", "data": [{"kills": 40, "shipTypeID": 29990}, {"kills": 12, "shipTypeID": 602}]}
+		]}`)
+	})
 
-	ships, err := GetTopShips(characterID)
+	ships, err := GetTopShips(context.Background(), 2117477599)
 	if err != nil {
 		t.Errorf("Error occurred: %v", err)
 		return
 	}
 
-	t.Logf("Ships: %v", ships)
+	if !reflect.DeepEqual(ships, []int{29990, 602}) {
+		t.Errorf("Expected ships: %v, got: %v", []int{29990, 602}, ships)
+	}
 }